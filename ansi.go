@@ -1,37 +1,278 @@
 package scroll
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
 
-// cursorUp uses an ANSI escape sequence to move the terminal's cursor position
-// up provided lines.
-func cursorUp(line int) {
-	_, err := fmt.Printf("\033[%dA", line)
+// ANSIRenderer is the default Renderer of a Buffer. It draws the scroll window
+// in place and uses ANSI escape sequences to erase it again. When the Window
+// is not a Terminal the output is left to a PlainRenderer instead.
+type ANSIRenderer struct {
+	w     io.Writer
+	plain *PlainRenderer
+
+	// Represents the number of rows currently drawn, including the pinned
+	// rows of the header and progress bars
+	rows   int
+	pinned int
+}
+
+// NewANSIRenderer creates a new ANSIRenderer writing to w.
+func NewANSIRenderer(w io.Writer) *ANSIRenderer {
+	return &ANSIRenderer{w: w, plain: NewPlainRenderer(w)}
+}
+
+// SetOutput sets the destination output for the ANSIRenderer.
+func (r *ANSIRenderer) SetOutput(w io.Writer) {
+	r.w = w
+	r.plain.SetOutput(w)
+}
+
+// Line prints the new line, redrawing the whole window once it is full.
+func (r *ANSIRenderer) Line(win Window, l Line) error {
+	if !win.IsTerm {
+		return r.plain.Line(win, l)
+	}
+	output := chunk(win.Text(l), win.width())
+
+	if r.pinned == win.pinnedCount() && r.rows-r.pinned+len(output) <= win.Size {
+		c := win.lineColorWriter(l)
+		for _, s := range output {
+			if _, err := c.Fprintln(r.w, s); err != nil {
+				return err
+			}
+			r.rows++
+		}
+		return flush(r.w)
+	}
+
+	if err := r.erase(win); err != nil {
+		return err
+	}
+	if err := r.draw(win); err != nil {
+		return err
+	}
+	return flush(r.w)
+}
+
+// StageBegin erases the window of the parent stage and draws the header of
+// the new stage.
+func (r *ANSIRenderer) StageBegin(win Window) error {
+	if !win.IsTerm {
+		return r.plain.StageBegin(win)
+	}
+	if err := r.erase(win); err != nil {
+		return err
+	}
+	if err := r.draw(win); err != nil {
+		return err
+	}
+	return flush(r.w)
+}
+
+// StageEnd erases the window and prints the stage message. A failed stage is
+// followed by every line written during the stage. The window of the parent
+// stage is drawn again afterwards.
+func (r *ANSIRenderer) StageEnd(win Window, s StageResult) error {
+	if !win.IsTerm {
+		return r.plain.StageEnd(win, s)
+	}
+	if err := r.erase(win); err != nil {
+		return err
+	}
+	if err := printStageEnd(r.w, win, s); err != nil {
+		return err
+	}
+	if err := r.draw(win); err != nil {
+		return err
+	}
+	return flush(r.w)
+}
+
+// Erase erases the window.
+func (r *ANSIRenderer) Erase(win Window) error {
+	if !win.IsTerm {
+		return r.plain.Erase(win)
+	}
+	if err := r.erase(win); err != nil {
+		return err
+	}
+	if err := r.draw(win); err != nil {
+		return err
+	}
+	return flush(r.w)
+}
+
+// Refresh redraws the header and progress bars in place to animate them. The
+// whole window is drawn again when the number of pinned rows changed.
+func (r *ANSIRenderer) Refresh(win Window) error {
+	if !win.IsTerm {
+		return nil
+	}
+	if r.pinned != win.pinnedCount() {
+		if err := r.erase(win); err != nil {
+			return err
+		}
+		if err := r.draw(win); err != nil {
+			return err
+		}
+		return flush(r.w)
+	}
+	if r.pinned == 0 {
+		return nil
+	}
+
+	if err := cursorUp(r.w, r.rows); err != nil {
+		return err
+	}
+	for _, row := range win.pinned(time.Now()) {
+		if err := clearEntireLine(r.w); err != nil {
+			return err
+		}
+		if _, err := row.color.Fprintln(r.w, row.text); err != nil {
+			return err
+		}
+	}
+	if r.rows > r.pinned {
+		if err := cursorDown(r.w, r.rows-r.pinned); err != nil {
+			return err
+		}
+	}
+	return flush(r.w)
+}
+
+// Close implements Renderer. The ANSIRenderer leaves its output as is, apart
+// from closing a folded group left open by its PlainRenderer.
+func (r *ANSIRenderer) Close() error {
+	return r.plain.Close()
+}
+
+// draw prints the pinned rows and the rows of the window below the cursor.
+func (r *ANSIRenderer) draw(win Window) error {
+	rows, err := drawWindow(r.w, win)
+	r.rows += rows
+	r.pinned = win.pinnedCount()
+	return err
+}
+
+// drawWindow prints the pinned rows and the rows of the Window, returning the
+// number of rows printed.
+func drawWindow(w io.Writer, win Window) (rows int, err error) {
+	for _, row := range win.pinned(time.Now()) {
+		if _, err := row.color.Fprintln(w, row.text); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+
+	for _, row := range win.rows() {
+		if _, err := row.color.Fprintln(w, row.text); err != nil {
+			return rows, err
+		}
+		rows++
+	}
+	return rows, nil
+}
+
+// printStageEnd prints the message of a finished stage, including its
+// duration when the Window shows a header. A failed stage is followed by
+// every line written during the stage.
+func printStageEnd(w io.Writer, win Window, s StageResult) error {
+	if err := printStageTitle(w, win, s); err != nil {
+		return err
+	}
+	if s.Status != StatusFail {
+		return nil
+	}
+	return dumpStageLog(w, win, s)
+}
+
+// printStageTitle prints the message of a finished stage, including its
+// duration when the Window shows a header.
+func printStageTitle(w io.Writer, win Window, s StageResult) error {
+	if s.Message == "" {
+		return nil
+	}
+	status := s.Status
+	title := s.Title()
+	if win.Header && win.IsTerm {
+		// the spinner of the header resolves into a check mark
+		if status == StatusNone {
+			status = StatusOK
+			title = StatusOK.Symbol() + " " + s.Message
+		}
+		title = fmt.Sprintf("%s (%s)", title, formatDuration(s.Duration()))
+	}
+	title = indentation(s.Depth) + title
+	_, err := win.getStatusWriter(status).Fprintln(w, title)
+	return err
+}
+
+// dumpStageLog prints every line written during a stage, or the lines picked
+// to be printed instead, indented below its message.
+func dumpStageLog(w io.Writer, win Window, s StageResult) error {
+	log := s.Log
+	if s.dump != nil {
+		log = s.dump
+	}
+	var err error
+	rangeErr := log.Range(func(l Line) bool {
+		_, err = win.lineColorWriter(l).Fprintln(w, indent(win.text(l, s.Depth), "  "))
+		return err == nil
+	})
 	if err != nil {
-		panic(err)
+		return err
 	}
+	return rangeErr
+}
+
+// erase erases all rows that are printed to the terminal.
+func (r *ANSIRenderer) erase(win Window) error {
+	lines := r.rows
+	r.rows = 0
+	r.pinned = 0
+	return eraseLines(r.w, lines)
+}
+
+// indent prefixes every line of s with the indentation.
+func indent(s, indentation string) string {
+	return indentation + strings.ReplaceAll(s, "\n", "\n"+indentation)
+}
+
+// cursorUp uses an ANSI escape sequence to move the terminal's cursor position
+// up provided lines.
+func cursorUp(w io.Writer, line int) error {
+	_, err := fmt.Fprintf(w, "\033[%dA", line)
+	return err
+}
+
+// cursorDown uses an ANSI escape sequence to move the terminal's cursor
+// position down provided lines.
+func cursorDown(w io.Writer, line int) error {
+	_, err := fmt.Fprintf(w, "\033[%dB", line)
+	return err
 }
 
 // clearEntireLine uses an ANSI escape sequence to delete the entire line of the
 // terminal.
-func clearEntireLine() {
-	_, err := fmt.Printf("\033[2K")
-	if err != nil {
-		panic(err)
-	}
+func clearEntireLine(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "\033[2K")
+	return err
 }
 
 // eraseLines scrolls up one line at a time from current position and clears
 // each line.
-func (b *Buffer) eraseLines(lines int) {
-	b.lock.Lock()
-	defer b.lock.Unlock()
-
-	if !b.isTerm {
-		return
-	}
-
+func eraseLines(w io.Writer, lines int) error {
 	for i := 1; i <= lines; i++ {
-		cursorUp(1)
-		clearEntireLine()
+		if err := cursorUp(w, 1); err != nil {
+			return err
+		}
+		if err := clearEntireLine(w); err != nil {
+			return err
+		}
 	}
+	return nil
 }
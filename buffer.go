@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"golang.org/x/term"
@@ -17,33 +19,69 @@ type Buffer struct {
 	// The max length of the visible output to the user
 	bufferMax int
 
-	// Represents the current buffer size
-	currentBufferSize int
-
 	// A prefix to print before each line
 	prefix string
 
 	// States whether the current fd is a Terminal
 	isTerm bool
 
+	// The terminal width used to wrap lines, zero detects it from stdout
+	width int
+
+	// States whether the stage header is shown above the scroll window
+	header bool
+
+	// What to print when the output is not a Terminal and the CI provider
+	// whose folding markers wrap each stage
+	policy Policy
+	ci     CI
+
 	// Set the color of output text
 	printerColor color.Attribute
 	stageColor   color.Attribute
+	stderrColor  color.Attribute
 
-	w      io.Writer
-	buffer []string
+	// Draws the lines and stages of the Buffer
+	renderer Renderer
 
-	// Internal synchronization variables
-	eraser  chan string
-	printer chan string
-	stagger chan struct{}
+	// The open stages, innermost last. The first stage is the unnamed stage
+	// used by NewStage and is never closed
+	stack []*stageState
 
-	lock *sync.RWMutex
-	ctx  context.Context
+	// The progress bars pinned above the scroll window
+	bars []*Progress
 
-	// Standard buffer synchronization requirements
-	stdBuffer bool
-	done      chan struct{}
+	// The finished stages and how many are kept at most, if limited, the
+	// number of bytes each stage log keeps in memory and the number of lines
+	// it keeps at most, if limited
+	stages     []StageResult
+	stageLimit int
+	budget     int
+	limit      int
+
+	// The first error returned by the renderer
+	err error
+
+	// The clock lines and stages are timed with
+	now func() time.Time
+
+	// Internal synchronization variables
+	eraser     chan struct{}
+	beginner   chan *stageState
+	stager     chan stageRequest
+	printer    chan lineRequest
+	progresser chan *Progress
+	stagger    chan struct{}
+	refresher  chan struct{}
+	closer     chan struct{}
+	closed     chan struct{}
+
+	lock      *sync.RWMutex
+	closeOnce *sync.Once
+	ctx       context.Context
+
+	// Signals the caller once its output has been written
+	done chan struct{}
 }
 
 var (
@@ -63,52 +101,104 @@ const DEFAULT_BUFFER_SIZE = 100
 // Default returns the standard buffer used by the package-level output functions.
 func Default() *Buffer { return std }
 
-// defaultBuffer is used to set the standard buffer internally.
+// defaultBuffer is used to set the standard buffer internally. It is never
+// closed, so it only keeps its last finished stage and never spills lines to
+// disk unless told otherwise.
 func defaultBuffer() *Buffer {
 	b := New(context.TODO(), os.Stdout, 15)
-	b.stdBuffer = true
+	b.SetStageLimit(1)
+	b.SetHistoryBudget(math.MaxInt)
 	return b
 }
 
 // New creates a new Buffer which starts a goroutine to print or erase lines and
-// cancels on context.Done(). Returns a new Buffer to allow for scroll output to
-// be written.
+// cancels on context.Done(), which closes the Buffer like Close does. Returns a
+// new Buffer to allow for scroll output to be written.
 func New(ctx context.Context, w io.Writer, bufferSize int) *Buffer {
+	return NewWithRenderer(ctx, NewANSIRenderer(w), bufferSize)
+}
+
+// NewWithRenderer creates a new Buffer like New, but draws its output with the
+// provided Renderer instead of an ANSIRenderer.
+func NewWithRenderer(ctx context.Context, r Renderer, bufferSize int) *Buffer {
 	b := &Buffer{
-		eraser:  make(chan string),
-		printer: make(chan string),
-		stagger: make(chan struct{}, bufferSize),
-		done:    make(chan struct{}),
-		isTerm:  IsTerm,
-
-		lock: &sync.RWMutex{},
-		ctx:  ctx,
+		eraser:     make(chan struct{}),
+		beginner:   make(chan *stageState),
+		stager:     make(chan stageRequest),
+		printer:    make(chan lineRequest),
+		progresser: make(chan *Progress),
+		stagger:    make(chan struct{}, bufferSize),
+		refresher:  make(chan struct{}, 1),
+		closer:     make(chan struct{}),
+		closed:     make(chan struct{}),
+		done:       make(chan struct{}),
+		isTerm:     IsTerm,
+		ci:         DetectCI(),
+
+		stderrColor: color.FgRed,
+
+		renderer: r,
+		stack:    []*stageState{newStageState("", nil, DEFAULT_HISTORY_BUDGET, 0)},
+		budget:   DEFAULT_HISTORY_BUDGET,
+
+		lock:      &sync.RWMutex{},
+		closeOnce: &sync.Once{},
+		ctx:       ctx,
+		now:       time.Now,
 	}
 
-	b.SetOutput(w)
 	b.SetBufferMax(bufferSize)
 
 	go func(buff *Buffer) {
-		defer close(b.printer)
-		defer close(buff.eraser)
-		defer close(b.stagger)
-		defer close(b.done)
+		// requests select on closed, so the other channels stay open
+		defer close(b.closed)
+
+		// the ticker only runs while there is something to animate
+		var ticker *time.Ticker
+		var tick <-chan time.Time
+		defer func() {
+			if ticker != nil {
+				ticker.Stop()
+			}
+		}()
 
 		for {
+			animate := buff.animated()
+			switch {
+			case animate && ticker == nil:
+				ticker = time.NewTicker(refreshInterval)
+				tick = ticker.C
+			case !animate && ticker != nil:
+				ticker.Stop()
+				ticker, tick = nil, nil
+			}
+
 			select {
+			case <-tick:
+				buff.refresh()
+			case <-b.refresher:
 			case p := <-b.printer:
-				buff.print(buff.prefix, p)
-				if b.stdBuffer {
-					b.done <- struct{}{}
-				}
-			case e := <-b.eraser:
+				buff.print(p)
+				b.done <- struct{}{}
+			case s := <-b.beginner:
+				buff.beginStage(s)
+				b.done <- struct{}{}
+			case s := <-b.stager:
+				buff.endStage(s)
+				b.done <- struct{}{}
+			case p := <-b.progresser:
+				buff.pin(p)
+				b.done <- struct{}{}
+			case <-b.eraser:
 				buff.eraseBuffer()
-				buff.buffer = []string{}
-				if strings.Compare("", e) != 0 {
-					buff.getColorWriter(EraserStage).Println(e)
-				}
 				b.done <- struct{}{}
+			case <-b.closer:
+				buff.setErr(buff.renderer.Close())
+				buff.closeLogs()
+				return
 			case <-b.ctx.Done():
+				buff.setErr(buff.renderer.Close())
+				buff.closeLogs()
 				return
 			}
 		}
@@ -116,71 +206,252 @@ func New(ctx context.Context, w io.Writer, bufferSize int) *Buffer {
 	return b
 }
 
-// print runs the logic required to actually print the output to the desired
-// line in a scrolling fashion.
-func (b *Buffer) print(a ...string) {
-	w := getBufferSize()
-	output := chunk(strings.TrimSpace(strings.Join(a, " ")), w)
+// A lineRequest asks the Buffer goroutine to print a line to a stage, or to
+// the current stage when stage is nil.
+type lineRequest struct {
+	text   string
+	stage  *stageState
+	stderr bool
+}
+
+// A stageRequest asks the Buffer goroutine to finish a stage, or the current
+// stage when stage is nil.
+type stageRequest struct {
+	result StageResult
+	stage  *stageState
+}
 
-	if len(b.buffer) > b.bufferMax {
+// current returns the innermost open stage.
+func (b *Buffer) current() *stageState {
+	return b.stack[len(b.stack)-1]
+}
+
+// print adds the line to the stage and hands it to the renderer if the stage
+// is the one shown in the scroll window.
+func (b *Buffer) print(req lineRequest) {
+	l := Line{Text: req.text, Time: b.now(), Stderr: req.stderr}
+
+	st := req.stage
+	if st == nil {
+		st = b.current()
+	}
+	st = b.open(st)
+	st.window = append(st.window, l)
+	b.setErr(st.log.append(l))
+	if len(st.window) > b.bufferMax {
 		// don't grow buffer more than needed
-		b.buffer = append(b.buffer[1:], output...)
-	} else {
-		b.buffer = append(b.buffer, output...)
+		st.window = st.window[len(st.window)-b.bufferMax:]
+	}
+	if st == b.current() {
+		b.setErr(b.renderer.Line(b.window(), l))
+	}
+}
+
+// open returns the stage, or its closest open parent if it is finished.
+func (b *Buffer) open(st *stageState) *stageState {
+	for st != nil && st.finished {
+		st = st.parent
+	}
+	if st == nil {
+		// the unnamed stage was replaced when it finished
+		return b.stack[0]
 	}
+	return st
+}
 
-	c := b.getColorWriter(PrinterStage)
-	if b.currentBufferSize+len(output) <= b.bufferMax {
-		for _, s := range output {
-			c.Fprintln(b.w, s)
-			b.currentBufferSize++
+// beginStage opens a new innermost stage inside its closest open parent,
+// finishing any stage begun inside that parent before.
+func (b *Buffer) beginStage(st *stageState) {
+	parent := b.open(st.parent)
+	for b.current() != parent {
+		child := b.current()
+		b.finish(child, StageResult{Message: child.name})
+	}
+	st.setParent(parent)
+	st.start = b.now()
+
+	b.lock.Lock()
+	b.stack = append(b.stack, st)
+	b.lock.Unlock()
+
+	b.setErr(b.renderer.StageBegin(b.window()))
+}
+
+// endStage finishes the requested stage with the provided outcome. Any stage
+// begun inside of it is finished first. Finishing the unnamed stage starts a
+// new one in its place.
+func (b *Buffer) endStage(req stageRequest) {
+	st := req.stage
+	if st == nil {
+		st = b.current()
+	}
+	if st.finished {
+		return
+	}
+	for b.current() != st {
+		child := b.current()
+		b.finish(child, StageResult{Message: child.name})
+	}
+	b.finish(st, req.result)
+}
+
+// finish records the result of the innermost stage and closes it.
+func (b *Buffer) finish(st *stageState, s StageResult) {
+	s.Name = st.name
+	s.Depth = st.depth
+	s.Start = st.start
+	s.End = b.now()
+	s.Log = st.log
+	if s.Message == "" {
+		s.Message = st.name
+	}
+
+	b.lock.Lock()
+	stored := s
+	stored.dump = nil
+	b.stages = append(b.stages, stored)
+	if b.stageLimit > 0 && len(b.stages) > b.stageLimit {
+		drop := len(b.stages) - b.stageLimit
+		for _, old := range b.stages[:drop] {
+			b.setErr(old.Log.Close())
 		}
+		b.stages = append([]StageResult(nil), b.stages[drop:]...)
+	}
+	if len(b.stack) == 1 {
+		b.stack[0] = newStageState("", nil, b.budget, b.limit)
+		b.stack[0].start = s.End
 	} else {
-		b.eraseBuffer()
-		for i := b.bufferMax; i > 0; i-- {
-			c.Fprintln(b.w, b.buffer[len(b.buffer)-i])
-			b.currentBufferSize++
-		}
+		b.stack = b.stack[:len(b.stack)-1]
 	}
+	b.lock.Unlock()
+
+	st.finished = true
+	b.setErr(b.renderer.StageEnd(b.window(), s))
+	b.setErr(st.log.release())
 }
 
 // eraseBuffer erases all lines that are printed to the terminal for the
 // existing Buffer.
 func (b *Buffer) eraseBuffer() {
-	if b.currentBufferSize == 0 {
-		return // nothing to erase
-	} else if b.currentBufferSize < b.bufferMax {
-		b.eraseLines(b.currentBufferSize)
-	} else {
-		b.eraseLines(b.bufferMax)
+	b.current().window = nil
+	b.setErr(b.renderer.Erase(b.window()))
+}
+
+// window returns a snapshot of the scroll window for the renderer.
+func (b *Buffer) window() Window {
+	st := b.current()
+	return Window{
+		Lines:        st.window,
+		Stage:        st.name,
+		Depth:        st.depth,
+		Start:        st.start,
+		Header:       b.header,
+		Progress:     b.progress(),
+		Size:         b.bufferMax,
+		Prefix:       b.prefix,
+		PrinterColor: b.printerColor,
+		StageColor:   b.stageColor,
+		StderrColor:  b.stderrColor,
+		IsTerm:       b.isTerm,
+		Width:        b.width,
+		Policy:       b.policy,
+		CI:           b.ci,
 	}
-	b.currentBufferSize = 0
 }
 
-func getBufferSize() int {
-	// dynamically checks to see if the buffer will go beyond the width limit of
-	// the terminal
-	w, _, err := term.GetSize(int(os.Stdout.Fd()))
-	if err != nil {
-		return DEFAULT_BUFFER_SIZE
+// progress returns a snapshot of the pinned progress bars.
+func (b *Buffer) progress() []ProgressState {
+	var states []ProgressState
+	for _, p := range b.bars {
+		states = append(states, p.state())
 	}
-	return w
+	return states
+}
+
+// setErr records the first error returned by the renderer.
+func (b *Buffer) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Close stops the Buffer, closes its Renderer and removes the spill files of
+// every stage log. It returns the first error the Buffer ran into. The Buffer
+// can't be written to afterwards.
+func (b *Buffer) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.closer)
+	})
+	<-b.closed
+	return b.err
+}
+
+// closeLogs closes the log of every stage, removing their spill files.
+func (b *Buffer) closeLogs() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for _, s := range b.stages {
+		b.setErr(s.Log.Close())
+	}
+	for _, st := range b.stack {
+		b.setErr(st.log.Close())
+	}
+}
+
+// StageLog returns the Log of the current stage.
+func (b *Buffer) StageLog() *Log {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.current().log
+}
+
+// Stages returns the stages that have been finished on the Buffer, oldest
+// first.
+func (b *Buffer) Stages() []StageResult {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return append([]StageResult(nil), b.stages...)
+}
+
+// stagesByBegin returns the finished stages in the order they were begun, so
+// every stage comes before the stages nested inside of it. Nested stages
+// finish before their parent, so the deeper trees finished last are moved
+// behind each stage.
+func (b *Buffer) stagesByBegin() []StageResult {
+	var trees [][]StageResult
+	for _, s := range b.Stages() {
+		i := len(trees)
+		for i > 0 && trees[i-1][0].Depth > s.Depth {
+			i--
+		}
+		tree := []StageResult{s}
+		for _, child := range trees[i:] {
+			tree = append(tree, child...)
+		}
+		trees = append(trees[:i], tree)
+	}
+
+	var stages []StageResult
+	for _, tree := range trees {
+		stages = append(stages, tree...)
+	}
+	return stages
 }
 
 // NewStage resets the Buffer by erasing the buffer output and printing out the
-// stage input to the screen.
+// stage input to the screen. When the current stage was begun with BeginStage
+// it is closed and its parent stage continues.
 func (b *Buffer) NewStage(format string, a ...interface{}) {
-	if b.bufferMax == 0 {
-		panic("your buffer hasn't been initialized!")
-	}
-	b.eraser <- fmt.Sprintf(format, a...)
-	<-b.done
+	b.finishStage(nil, StageResult{Message: fmt.Sprintf(format, a...)})
 }
 
 // EraseBuffer is the exported function that includes Buffer validations.
 func (b *Buffer) EraseBuffer() {
-	b.eraser <- ""
-	<-b.done
+	select {
+	case b.eraser <- struct{}{}:
+		<-b.done
+	case <-b.closed:
+	}
 }
 
 // GetBufferSize returns the current bufferSize of the Buffer.
@@ -188,26 +459,35 @@ func (b *Buffer) GetBufferSize() int {
 	return b.bufferMax
 }
 
-// Printf safely executes the channel printing logic and formats the provided
-// string to the temporary buffer.
-func (b *Buffer) Printf(format string, a ...interface{}) {
-	b.stagger <- struct{}{}
+// printTo prints the line to the stage of the request. Lines printed once the
+// Buffer is closed are dropped.
+func (b *Buffer) printTo(req lineRequest) {
+	select {
+	case b.stagger <- struct{}{}:
+	case <-b.closed:
+		return
+	}
 	defer func() {
 		<-b.stagger
 	}()
 
-	b.printer <- fmt.Sprintf(format, a...)
+	select {
+	case b.printer <- req:
+		<-b.done
+	case <-b.closed:
+	}
+}
+
+// Printf safely executes the channel printing logic and formats the provided
+// string to the temporary buffer.
+func (b *Buffer) Printf(format string, a ...interface{}) {
+	b.printTo(lineRequest{text: fmt.Sprintf(format, a...)})
 }
 
 // Println safely executes the channel printing logic and formats the provided
 // string to the temporary buffer.
 func (b *Buffer) Println(a ...interface{}) {
-	b.stagger <- struct{}{}
-	defer func() {
-		<-b.stagger
-	}()
-
-	b.printer <- fmt.Sprint(a...)
+	b.printTo(lineRequest{text: fmt.Sprint(a...)})
 }
 
 // SetBufferMax sets the size of the Buffer.
@@ -215,9 +495,59 @@ func (b *Buffer) SetBufferMax(size int) {
 	b.bufferMax = size
 }
 
-// SetOutput sets the destination output for the Buffer.
+// SetHistoryBudget sets the number of bytes each stage Log of the Buffer keeps
+// in memory before spilling lines to a temporary file. It applies to the
+// current stage if nothing was printed to it yet, and to every stage after.
+func (b *Buffer) SetHistoryBudget(bytes int) {
+	b.budget = bytes
+	b.applyHistory()
+}
+
+// SetHistoryLimit limits each stage Log of the Buffer to its last lines,
+// dropping older lines instead of spilling them to a temporary file, for
+// stages that can run for as long as the process does. A limit of zero keeps
+// every line. It applies to the current stage if nothing was printed to it
+// yet, and to every stage after.
+func (b *Buffer) SetHistoryLimit(lines int) {
+	b.limit = lines
+	b.applyHistory()
+}
+
+// SetStageLimit limits the finished stages the Buffer keeps to the last ones,
+// closing the Log of older stages and removing their spill files. A limit of
+// zero keeps every stage, which is the default except for the standard
+// Buffer, that keeps one.
+func (b *Buffer) SetStageLimit(stages int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.stageLimit = stages
+}
+
+// applyHistory applies the history budget and limit to the current stage if
+// nothing was printed to it yet.
+func (b *Buffer) applyHistory() {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	b.current().log.setLimits(b.budget, b.limit)
+}
+
+// SetIsTerm overrides the terminal check for the Buffer. ANSI escape
+// sequences and colors are only written when isTerm is true.
+func (b *Buffer) SetIsTerm(isTerm bool) {
+	b.isTerm = isTerm
+}
+
+// SetOutput sets the destination output for the Buffer, if its Renderer
+// supports changing it.
 func (b *Buffer) SetOutput(w io.Writer) {
-	b.w = w
+	if r, ok := b.renderer.(interface{ SetOutput(io.Writer) }); ok {
+		r.SetOutput(w)
+	}
+}
+
+// SetPolicy sets what the Buffer prints when its output is not a Terminal.
+func (b *Buffer) SetPolicy(p Policy) {
+	b.policy = p
 }
 
 // SetPrefix sets the prefix for output from the Buffer.
@@ -235,23 +565,42 @@ func (b *Buffer) SetStageColor(color color.Attribute) {
 	b.stageColor = color
 }
 
+// SetStderrColor sets the output color for lines written to stderr by a
+// command run with Run on the Buffer.
+func (b *Buffer) SetStderrColor(color color.Attribute) {
+	b.stderrColor = color
+}
+
+// SetWidth sets the terminal width used to wrap lines on the Buffer. A width
+// of zero detects the width of stdout.
+func (b *Buffer) SetWidth(width int) {
+	b.width = width
+}
+
+// SetClock sets the clock the lines and stages of the Buffer are timed with,
+// such as to replay a recording with its own times. It must be set before
+// anything is written to the Buffer, and restarts the current stage.
+func (b *Buffer) SetClock(now func() time.Time) {
+	b.now = now
+	b.current().start = now()
+}
+
 // Write implements io.Writer for Buffer to be used as output in other types.
 // This functionality is EXPERIMENTAL. The inherent channels aren't copied over
 // properly to most packages, so behavior isn't as expected.
 func (b *Buffer) Write(p []byte) (n int, err error) {
-	b.stagger <- struct{}{}
-	defer func() {
-		<-b.stagger
-	}()
-
-	b.printer <- fmt.Sprint(strings.TrimSpace(string(p)))
+	b.printTo(lineRequest{text: strings.TrimSpace(string(p))})
 	return len(p), nil
 }
 
+// Close closes the standard Buffer, removing the spill files of its stage logs.
+func Close() error {
+	return std.Close()
+}
+
 // EraseBuffer is the exported function that includes Buffer validations.
 func EraseBuffer() {
-	std.eraser <- ""
-	<-std.done
+	std.EraseBuffer()
 }
 
 // GetBufferMax returns the current maximum buffer length of the standard
@@ -263,32 +612,19 @@ func GetBufferMax() int {
 // Resets the Buffer buffer by erasing buffer output and printing out the string
 // input to the screen for the standard buffer.
 func NewStage(format string, a ...interface{}) {
-	std.eraser <- fmt.Sprintf(format, a...)
-	<-std.done
+	std.NewStage(format, a...)
 }
 
 // Printf safely executes the channel printing logic and formats the provided
 // string to the standard buffer.
 func Printf(format string, a ...interface{}) {
-	std.stagger <- struct{}{}
-	defer func() {
-		<-std.stagger
-	}()
-
-	std.printer <- fmt.Sprintf(format, a...)
-	<-std.done
+	std.Printf(format, a...)
 }
 
 // Println safely executes the channel printing logic and formats the provided
 // string to the standard buffer.
 func Println(a ...interface{}) {
-	std.stagger <- struct{}{}
-	defer func() {
-		<-std.stagger
-	}()
-
-	std.printer <- fmt.Sprint(a...)
-	<-std.done
+	std.Println(a...)
 }
 
 // SetBufferMax sets the buffer size of the standard Buffer.
@@ -303,7 +639,13 @@ func SetContext(ctx context.Context) {
 
 // SetOutput sets the destination output for the standard Buffer.
 func SetOutput(w io.Writer) {
-	std.w = w
+	std.SetOutput(w)
+}
+
+// SetPolicy sets what the standard Buffer prints when its output is not a
+// Terminal.
+func SetPolicy(p Policy) {
+	std.SetPolicy(p)
 }
 
 // SetPrefix sets the prefix for output from the standard Buffer.
@@ -323,52 +665,8 @@ func SetStageColor(color color.Attribute) {
 	std.stageColor = color
 }
 
-// Custom stage type for color function
-type stage string
-
-const (
-	PrinterStage stage = "PRINTER"
-	EraserStage  stage = "ERASER"
-)
-
-// getColorWriter gets the color set in the Buffer based on the stage.
-func (b *Buffer) getColorWriter(s stage) *color.Color {
-	var c color.Attribute
-	switch s {
-	case EraserStage:
-		c = b.stageColor
-	default:
-		c = b.printerColor
-	}
-	return color.New(c)
-}
-
-// chunk splits a provided string by newline characters and by the maximum
-// length of the buffer. A string list of plaintext strings that fit in each
-// buffer line is returned.
-func chunk(s string, chunkSize int) []string {
-	if len(s) == 0 {
-		return nil
-	}
-
-	splits := strings.Split(s, "\n")
-	if chunkSize >= len(s) {
-		return splits
-	}
-	var chunks []string
-
-	for _, split := range splits {
-		currentLen := 0
-		currentStart := 0
-		for i := range split {
-			if currentLen == chunkSize {
-				chunks = append(chunks, s[currentStart:i])
-				currentLen = 0
-				currentStart = i
-			}
-			currentLen++
-		}
-		chunks = append(chunks, s[currentStart:])
-	}
-	return chunks
+// SetStderrColor sets the output color for lines written to stderr on the
+// standard Buffer.
+func SetStderrColor(color color.Attribute) {
+	std.stderrColor = color
 }
@@ -7,12 +7,14 @@ import (
 	"math/rand"
 	"os"
 	"os/signal"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/louislef299/scroll"
+	"github.com/louislef299/scroll/scrolltest"
 	"golang.org/x/term"
 )
 
@@ -22,6 +24,69 @@ func TestBufferCreation(t *testing.T) {
 	b.Println("hello world")
 }
 
+func TestBufferStagesScreen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	term := scrolltest.New(40, 10)
+	buff := scroll.New(ctx, term, 3)
+	buff.SetIsTerm(true)
+	buff.SetWidth(40)
+	buff.SetPrefix("=>")
+	buff.SetStageColor(color.FgGreen)
+
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 5; j++ {
+			buff.Printf("%d: hello world", j)
+		}
+		buff.NewStage("stage %d finished!", i)
+	}
+
+	want := []string{"stage 0 finished!", "stage 1 finished!"}
+	if got := term.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+	if got := term.Row(0)[0].Attr.FG; got != "32" {
+		t.Fatalf("expected stage color 32, got %q", got)
+	}
+}
+
+func TestBufferWrapScreen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	term := scrolltest.New(10, 5)
+	buff := scroll.New(ctx, term, 2)
+	buff.SetIsTerm(true)
+	buff.SetWidth(10)
+
+	buff.Println("abcdefghijklmnop\nqrstuvwxyz")
+	buff.NewStage("done")
+
+	if got, want := term.Lines(), []string{"done"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+}
+
+func TestBufferStagesGolden(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	term := scrolltest.New(30, 6)
+	buff := scroll.New(ctx, term, 3)
+	buff.SetIsTerm(true)
+	buff.SetWidth(30)
+	buff.SetPrefix("=>")
+	buff.SetPrinterColor(color.FgHiMagenta)
+	buff.SetStageColor(color.FgGreen)
+
+	for i := 0; i < 2; i++ {
+		runSampleStage(buff, 5, time.Millisecond)
+		buff.NewStage("=>=> stage %d finished!", i)
+	}
+	scrolltest.AssertGolden(t, "stages", term.Golden())
+}
+
 func TestStandardBufferTicker(t *testing.T) {
 	fmt.Println("Testing the Standard Buffer:")
 	scroll.SetPrefix("()=>")
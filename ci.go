@@ -0,0 +1,123 @@
+package scroll
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// A CI is a continuous integration provider whose log viewer can fold the
+// output of a stage.
+type CI int
+
+const (
+	NoCI CI = iota
+	GitHubActions
+	GitLabCI
+	Buildkite
+	AzurePipelines
+	TeamCity
+)
+
+// String returns the name of the CI provider.
+func (ci CI) String() string {
+	switch ci {
+	case GitHubActions:
+		return "GitHub Actions"
+	case GitLabCI:
+		return "GitLab CI"
+	case Buildkite:
+		return "Buildkite"
+	case AzurePipelines:
+		return "Azure Pipelines"
+	case TeamCity:
+		return "TeamCity"
+	default:
+		return "none"
+	}
+}
+
+// DetectCI detects the CI provider the process runs in from the environment
+// variables each provider sets. NoCI is returned when none is found.
+func DetectCI() CI {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return GitHubActions
+	case os.Getenv("GITLAB_CI") == "true":
+		return GitLabCI
+	case os.Getenv("BUILDKITE") == "true":
+		return Buildkite
+	case strings.EqualFold(os.Getenv("TF_BUILD"), "true"):
+		return AzurePipelines
+	case os.Getenv("TEAMCITY_VERSION") != "":
+		return TeamCity
+	default:
+		return NoCI
+	}
+}
+
+// openGroup writes the marker that starts a folded group of output with the
+// title at the start time. The section is a unique name for providers that
+// need one. Groups are opened before the outcome of their stage is known, so
+// they are collapsed.
+func (ci CI) openGroup(w io.Writer, title, section string, start time.Time) error {
+	var err error
+	switch ci {
+	case GitHubActions:
+		_, err = fmt.Fprintf(w, "::group::%s\n", title)
+	case GitLabCI:
+		_, err = fmt.Fprintf(w, "\033[0Ksection_start:%d:%s[collapsed=true]\r\033[0K%s\n",
+			start.Unix(), section, title)
+	case Buildkite:
+		_, err = fmt.Fprintf(w, "--- %s\n", title)
+	case AzurePipelines:
+		_, err = fmt.Fprintf(w, "##[group]%s\n", title)
+	case TeamCity:
+		_, err = fmt.Fprintf(w, "##teamcity[blockOpened name='%s']\n", teamCityEscape(title))
+	}
+	return err
+}
+
+// closeGroup writes the marker that ends a folded group of output at the end
+// time.
+func (ci CI) closeGroup(w io.Writer, title, section string, end time.Time) error {
+	var err error
+	switch ci {
+	case GitHubActions:
+		_, err = fmt.Fprintln(w, "::endgroup::")
+	case GitLabCI:
+		_, err = fmt.Fprintf(w, "\033[0Ksection_end:%d:%s\r\033[0K\n", end.Unix(), section)
+	case AzurePipelines:
+		_, err = fmt.Fprintln(w, "##[endgroup]")
+	case TeamCity:
+		_, err = fmt.Fprintf(w, "##teamcity[blockClosed name='%s']\n", teamCityEscape(title))
+	}
+	// Buildkite groups end where the next one starts
+	return err
+}
+
+// teamCityEscape escapes a value of a TeamCity service message.
+func teamCityEscape(s string) string {
+	return strings.NewReplacer(
+		"|", "||",
+		"'", "|'",
+		"\n", "|n",
+		"\r", "|r",
+		"[", "|[",
+		"]", "|]",
+	).Replace(s)
+}
+
+// SetCI sets the CI provider whose folding markers wrap the output of each
+// stage as it is printed when the output of the Buffer is not a Terminal and
+// its Policy is Passthrough. It defaults to the result of DetectCI.
+func (b *Buffer) SetCI(ci CI) {
+	b.ci = ci
+}
+
+// SetCI sets the CI provider of the standard Buffer.
+func SetCI(ci CI) {
+	std.SetCI(ci)
+}
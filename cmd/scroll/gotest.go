@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/louislef299/scroll"
+)
+
+// gotest shows the output of go test -json with every package as a stage.
+func gotest(args []string) error {
+	fs := flag.NewFlagSet("gotest", flag.ContinueOnError)
+	var wf windowFlags
+	wf.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: go test -json [packages] | scroll gotest [flags]")
+		fmt.Fprintln(fs.Output(), "       scroll gotest [flags] -- [go test flags] [packages]")
+		fmt.Fprintln(fs.Output(), "\nShows every package as a stage with the output of its running tests in a")
+		fmt.Fprintln(fs.Output(), "scroll window. Passing packages collapse to a single line and failing packages")
+		fmt.Fprintln(fs.Output(), "are followed by the output of their failing tests. With arguments, go test")
+		fmt.Fprintln(fs.Output(), "-json is run with them instead of reading stdin.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return gotestTo(os.Stdin, os.Stdout, wf)
+	}
+
+	cmd := exec.Command("go", append([]string{"test", "-json"}, fs.Args()...)...)
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	// build errors are written to stderr as plain text
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		pw.CloseWithError(cmd.Wait())
+	}()
+
+	err := gotestTo(pr, os.Stdout, wf)
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		// go test fails along with its packages
+		return &exitError{code: 1}
+	}
+	return err
+}
+
+// gotestTo shows the go test -json output of r with a Buffer writing to w.
+func gotestTo(r io.Reader, w io.Writer, wf windowFlags) error {
+	buff, _, err := wf.buffer(context.Background(), w)
+	if err != nil {
+		return err
+	}
+	ok, err := scroll.GoTest(buff, r)
+	if closeErr := buff.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &exitError{code: 1}
+	}
+	return nil
+}
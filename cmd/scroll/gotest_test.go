@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+func TestGotest(t *testing.T) {
+	forceTerm(t)
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+		code  int
+	}{
+		{
+			name: "pass",
+			input: `{"Action":"output","Package":"example.com/a","Output":"ok  \texample.com/a\t0.1s\n"}
+{"Action":"pass","Package":"example.com/a","Elapsed":0.1}`,
+			want: []string{"ok example.com/a (0.1s)"},
+		},
+		{
+			name: "fail",
+			input: `{"Action":"output","Package":"example.com/a","Output":"FAIL\texample.com/a\t0.1s\n"}
+{"Action":"fail","Package":"example.com/a","Elapsed":0.1}`,
+			want: []string{"✖ FAIL example.com/a (0.1s)", "  FAIL  example.com/a   0.1s"},
+			code: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := scrolltest.New(60, 10)
+			err := gotestTo(strings.NewReader(tt.input), term, windowFlags{size: 3})
+
+			var exit *exitError
+			switch {
+			case tt.code == 0 && err != nil:
+				t.Fatal(err)
+			case tt.code != 0 && (!errors.As(err, &exit) || exit.code != tt.code):
+				t.Fatalf("expected exit status %d, got %v", tt.code, err)
+			}
+			if got := term.Lines(); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("expected screen\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Command scroll shows the output of other programs in a scroll window and
+// works with the sessions recorded by the scroll package.
+//
+// Usage:
+//
+//	command | scroll [flags]
+//	scroll <command> [flags] [args]
+//
+// Without a command, scroll shows the lines of stdin in a scroll window that
+// collapses to a single line once stdin is closed. The commands are:
+//
+//	gotest    show go test -json output with packages as stages
+//	pipeline  run the steps of a pipeline file as stages
+//	replay    play back a recorded session
+//	run       run a command in a scroll window
+//	tail      follow files in scroll windows
+//	watch     rerun a command with its latest output in a scroll window
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A command is a subcommand of scroll.
+type command struct {
+	run     func(args []string) error
+	summary string
+}
+
+var commands = map[string]command{
+	"gotest":   {gotest, "show go test -json output with packages as stages"},
+	"pipeline": {pipeline, "run the steps of a pipeline file as stages"},
+	"replay":   {replay, "play back a recorded session"},
+	"run":      {runCmd, "run a command in a scroll window"},
+	"tail":     {tail, "follow files in scroll windows"},
+	"watch":    {watch, "rerun a command with its latest output in a scroll window"},
+}
+
+// An exitError ends scroll with its exit code after the failure has already
+// been shown to the user.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func main() {
+	if err := dispatch(os.Args[1:]); err != nil {
+		var exit *exitError
+		switch {
+		case errors.Is(err, flag.ErrHelp):
+			os.Exit(0)
+		case errors.As(err, &exit):
+			os.Exit(exit.code)
+		}
+		fmt.Fprintln(os.Stderr, "scroll:", err)
+		os.Exit(1)
+	}
+}
+
+// dispatch hands the arguments to their subcommand. Arguments that don't
+// start with a command are the flags of the stdin pipe.
+func dispatch(args []string) error {
+	if len(args) > 0 {
+		if args[0] == "help" {
+			usage(os.Stdout)
+			return flag.ErrHelp
+		}
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd.run(args[1:])
+		}
+		if !strings.HasPrefix(args[0], "-") {
+			usage(os.Stderr)
+			return fmt.Errorf("unknown command %q", args[0])
+		}
+	}
+	return pipe(args)
+}
+
+// usage prints the commands of scroll to w.
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "usage: command | scroll [flags]")
+	fmt.Fprintln(w, "       scroll <command> [flags] [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s%s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w, "\nrun 'scroll -h' for the flags of the pipe and 'scroll <command> -h' for")
+	fmt.Fprintln(w, "the flags of a command")
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/louislef299/scroll"
+)
+
+// windowFlags are the flags of the commands that show lines in a scroll
+// window.
+type windowFlags struct {
+	size        int
+	prefix      string
+	failPattern string
+	message     string
+}
+
+// register defines the flags of the scroll window on fs.
+func (f *windowFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.size, "n", 5, "number of lines shown in the scroll window")
+	fs.StringVar(&f.prefix, "prefix", "", "`prefix` printed before each line")
+}
+
+// registerOutcome defines the flags deciding how the scroll window collapses
+// on fs. The final message defaults to the description of fallback.
+func (f *windowFlags) registerOutcome(fs *flag.FlagSet, fallback string) {
+	fs.StringVar(&f.failPattern, "fail-pattern", "", "fail and dump every line when a line matches the `regexp`")
+	fs.StringVar(&f.message, "m", "", "final `message`, defaults to "+fallback)
+}
+
+// buffer creates a Buffer writing to w with the flags, and compiles the fail
+// pattern, which is nil when it is empty.
+func (f *windowFlags) buffer(ctx context.Context, w io.Writer) (*scroll.Buffer, *regexp.Regexp, error) {
+	if f.size < 1 {
+		return nil, nil, fmt.Errorf("invalid scroll window size %d", f.size)
+	}
+	var fail *regexp.Regexp
+	if f.failPattern != "" {
+		var err error
+		if fail, err = regexp.Compile(f.failPattern); err != nil {
+			return nil, nil, fmt.Errorf("invalid fail pattern: %w", err)
+		}
+	}
+	buff := scroll.New(ctx, w, f.size)
+	buff.SetPrefix(f.prefix)
+	return buff, fail, nil
+}
+
+// A follower prints lines to a scroll window while watching for the fail
+// pattern.
+type follower struct {
+	print func(format string, a ...interface{})
+	fail  *regexp.Regexp
+
+	// The last line printed and the first line that matched the fail pattern
+	last    string
+	matched string
+}
+
+// follow prints every line of r until it is closed.
+func (f *follower) follow(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<30)
+	for s.Scan() {
+		f.line(s.Text())
+	}
+	return s.Err()
+}
+
+// line prints a single line.
+func (f *follower) line(text string) {
+	f.print("%s", text)
+	f.last = text
+	if f.matched == "" && f.fail != nil && f.fail.MatchString(text) {
+		f.matched = text
+	}
+}
+
+// pipe shows the lines of stdin in a scroll window.
+func pipe(args []string) error {
+	fs := flag.NewFlagSet("scroll", flag.ContinueOnError)
+	var wf windowFlags
+	wf.register(fs)
+	wf.registerOutcome(fs, "the last line")
+	fs.Usage = func() {
+		usage(fs.Output())
+		fmt.Fprintln(fs.Output(), "\nWithout a command, the lines of stdin are shown in a scroll window that")
+		fmt.Fprintln(fs.Output(), "collapses to a single line once stdin is closed.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return pipeTo(os.Stdin, os.Stdout, wf)
+}
+
+// pipeTo shows the lines of r in a scroll window written to w. Once r is
+// closed the window collapses to the final message, or to the first line that
+// matched the fail pattern followed by every line.
+func pipeTo(r io.Reader, w io.Writer, wf windowFlags) error {
+	buff, fail, err := wf.buffer(context.Background(), w)
+	if err != nil {
+		return err
+	}
+	f := &follower{print: buff.Printf, fail: fail}
+
+	if err := f.follow(r); err != nil {
+		buff.StageFail(err)
+		buff.Close()
+		return &exitError{code: 1}
+	}
+	if f.matched != "" {
+		buff.StageFail(fmt.Errorf("%s", f.matched))
+		buff.Close()
+		return &exitError{code: 1}
+	}
+
+	msg := wf.message
+	if msg == "" {
+		msg = f.last
+	}
+	if msg == "" {
+		buff.EraseBuffer()
+	} else {
+		buff.NewStage("%s", msg)
+	}
+	return buff.Close()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/louislef299/scroll"
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+// forceTerm makes new Buffers draw their scroll window for the test.
+func forceTerm(t *testing.T) {
+	isTerm := scroll.IsTerm
+	scroll.IsTerm = true
+	t.Cleanup(func() { scroll.IsTerm = isTerm })
+}
+
+func TestPipe(t *testing.T) {
+	forceTerm(t)
+	tests := []struct {
+		name  string
+		flags windowFlags
+		input string
+		want  []string
+		code  int
+	}{
+		{
+			name:  "collapse",
+			flags: windowFlags{size: 2, prefix: "=>"},
+			input: "one\ntwo\nthree\n",
+			want:  []string{"three"},
+		},
+		{
+			name:  "message",
+			flags: windowFlags{size: 2, message: "built"},
+			input: "one\ntwo\n",
+			want:  []string{"built"},
+		},
+		{
+			name:  "fail pattern",
+			flags: windowFlags{size: 2, prefix: "=>", failPattern: "^error"},
+			input: "one\nerror: two\nthree\n",
+			want:  []string{"✖ error: two", "  => one", "  => error: two", "  => three"},
+			code:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := scrolltest.New(40, 10)
+			err := pipeTo(strings.NewReader(tt.input), term, tt.flags)
+
+			var exit *exitError
+			switch {
+			case tt.code == 0 && err != nil:
+				t.Fatal(err)
+			case tt.code != 0 && (!errors.As(err, &exit) || exit.code != tt.code):
+				t.Fatalf("expected exit status %d, got %v", tt.code, err)
+			}
+			if got := term.Lines(); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("expected screen\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
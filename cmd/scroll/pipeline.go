@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/louislef299/scroll"
+)
+
+// A pipelineFile declares the steps of a pipeline.
+type pipelineFile struct {
+	Name  string         `json:"name"`
+	Steps []pipelineStep `json:"steps"`
+
+	// The directory relative step directories are resolved against
+	dir string
+}
+
+// A pipelineStep is a command run as a stage of a pipeline. The command is
+// either a shell command line or a list of arguments run without a shell.
+type pipelineStep struct {
+	Name            string            `json:"name"`
+	Command         string            `json:"command"`
+	Args            []string          `json:"args"`
+	Dir             string            `json:"dir"`
+	Env             map[string]string `json:"env"`
+	Timeout         string            `json:"timeout"`
+	ContinueOnError bool              `json:"continue_on_error"`
+
+	timeout time.Duration
+}
+
+// pipeline runs the steps of a pipeline file as stages.
+func pipeline(args []string) error {
+	fs := flag.NewFlagSet("pipeline", flag.ContinueOnError)
+	var wf windowFlags
+	wf.register(fs)
+	wf.registerOutcome(fs, "the pipeline name")
+	file := fs.String("f", "", "pipeline `file`, JSON or TOML by its extension")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: scroll pipeline [flags] -f <file>")
+		fmt.Fprintln(fs.Output(), "\nRuns the steps of the pipeline file one after another as stages and prints a")
+		fmt.Fprintln(fs.Output(), "summary. Steps after a failed step are skipped unless it has continue_on_error.")
+		fmt.Fprintln(fs.Output(), "\nA step has a name, a command or args, and optionally a dir, env, timeout such")
+		fmt.Fprintln(fs.Output(), "as \"5m\" and continue_on_error:")
+		fmt.Fprintln(fs.Output(), "\n  [[steps]]")
+		fmt.Fprintln(fs.Output(), "  name = \"test\"")
+		fmt.Fprintln(fs.Output(), "  command = \"go test ./...\"")
+		fmt.Fprintln(fs.Output(), "  env = { CGO_ENABLED = \"0\" }")
+		fmt.Fprintln(fs.Output(), "  timeout = \"10m\"")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("pipeline needs exactly one -f file")
+	}
+
+	p, err := loadPipeline(*file)
+	if err != nil {
+		return err
+	}
+	return pipelineTo(context.Background(), os.Stdout, wf, p)
+}
+
+// loadPipeline reads and validates a pipeline file. Files with a .toml
+// extension are TOML, any other file is JSON.
+func loadPipeline(path string) (*pipelineFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		var doc map[string]interface{}
+		if _, err := toml.Decode(string(data), &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &pipelineFile{dir: filepath.Dir(path)}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// validate checks the steps of the pipeline and parses their timeouts.
+func (p *pipelineFile) validate() error {
+	if len(p.Steps) == 0 {
+		return errors.New("pipeline has no steps")
+	}
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		if s.Name == "" {
+			return fmt.Errorf("step %d has no name", i+1)
+		}
+		if (s.Command == "") == (len(s.Args) == 0) {
+			return fmt.Errorf("step %q needs either a command or args", s.Name)
+		}
+		if s.Timeout != "" {
+			d, err := time.ParseDuration(s.Timeout)
+			if err != nil {
+				return fmt.Errorf("step %q: invalid timeout: %w", s.Name, err)
+			}
+			s.timeout = d
+		}
+	}
+	return nil
+}
+
+// pipelineTo runs the steps of the pipeline as stages of a Buffer written to
+// w, followed by a summary of every step.
+func pipelineTo(ctx context.Context, w io.Writer, wf windowFlags, p *pipelineFile) error {
+	buff, fail, err := wf.buffer(ctx, w)
+	if err != nil {
+		return err
+	}
+	defer buff.Close()
+
+	var exit *exitError
+	for _, s := range p.Steps {
+		st := buff.BeginStage(s.Name)
+		if exit != nil {
+			st.Skip("")
+			continue
+		}
+
+		code, err := s.run(ctx, p.dir, st, fail)
+		switch {
+		case err == nil:
+			st.OK("")
+		case s.ContinueOnError:
+			st.Fail(fmt.Errorf("%s: %w (continued)", s.Name, err))
+		default:
+			st.Fail(fmt.Errorf("%s: %w", s.Name, err))
+			exit = &exitError{code: code}
+		}
+	}
+
+	stages := buff.Stages()
+	if err := buff.Close(); err != nil {
+		return err
+	}
+	title := wf.message
+	if title == "" {
+		title = p.Name
+	}
+	if err := summarize(w, title, stages, exit != nil); err != nil {
+		return err
+	}
+	if exit != nil {
+		return exit
+	}
+	return nil
+}
+
+// run runs the step with its output printed to the stage. The exit status
+// is that of the command, or 1 if it failed otherwise.
+func (s pipelineStep) run(ctx context.Context, dir string, st *scroll.Stage, fail *regexp.Regexp) (int, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	argv := s.Args
+	if s.Command != "" {
+		argv = []string{"sh", "-c", s.Command}
+		if runtime.GOOS == "windows" {
+			argv = []string{"cmd", "/C", s.Command}
+		}
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	if s.Dir != "" {
+		cmd.Dir = s.Dir
+		if !filepath.IsAbs(s.Dir) {
+			cmd.Dir = filepath.Join(dir, s.Dir)
+		}
+	}
+	if len(s.Env) > 0 {
+		keys := make([]string, 0, len(s.Env))
+		for k := range s.Env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		cmd.Env = os.Environ()
+		for _, k := range keys {
+			cmd.Env = append(cmd.Env, k+"="+s.Env[k])
+		}
+	}
+
+	if err := st.Exec(ctx, cmd); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return 1, fmt.Errorf("timed out after %s", s.timeout)
+		}
+		return exitCode(cmd, err), err
+	}
+	if matched := firstMatch(st.Log(), fail); matched != "" {
+		return 1, errors.New(matched)
+	}
+	return 0, nil
+}
+
+// firstMatch returns the first line of the log that matches the fail
+// pattern, or an empty string if none does or the pattern is nil.
+func firstMatch(l *scroll.Log, fail *regexp.Regexp) string {
+	var matched string
+	if fail == nil {
+		return matched
+	}
+	l.Range(func(line scroll.Line) bool {
+		if fail.MatchString(line.Text) {
+			matched = line.Text
+		}
+		return matched == ""
+	})
+	return matched
+}
+
+// summarize prints the status and duration of every step, preceded by the
+// title and totals of the pipeline. A pipeline whose failed steps all
+// continued on error is shown with a warning.
+func summarize(w io.Writer, title string, stages []scroll.StageResult, failedHard bool) error {
+	var failed, skipped int
+	var total time.Duration
+	for _, s := range stages {
+		switch s.Status {
+		case scroll.StatusFail:
+			failed++
+		case scroll.StatusSkip:
+			skipped++
+		}
+		total += s.Duration()
+	}
+
+	status := scroll.StatusOK
+	switch {
+	case failedHard:
+		status = scroll.StatusFail
+	case failed > 0:
+		status = scroll.StatusWarn
+	}
+	fmt.Fprintf(w, "%s %s: %d steps, %d failed, %d skipped (%s)\n",
+		status.Symbol(), title, len(stages), failed, skipped, total.Round(100*time.Millisecond))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range stages {
+		duration := s.Duration().Round(100 * time.Millisecond).String()
+		if s.Status == scroll.StatusSkip {
+			duration = "skipped"
+		}
+		fmt.Fprintf(tw, "  %s %s\t%s\n", s.Status.Symbol(), s.Name, duration)
+	}
+	return tw.Flush()
+}
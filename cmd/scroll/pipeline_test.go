@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+func TestLoadPipelineTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ci.toml")
+	err := os.WriteFile(path, []byte(`name = "ci"
+
+[[steps]]
+name = "build"
+command = """
+go vet ./...
+go build ./..."""
+env.GOOS = "linux"
+env.CGO_ENABLED = "0"
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := loadPipeline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := pipelineStep{
+		Name:    "build",
+		Command: "go vet ./...\ngo build ./...",
+		Env:     map[string]string{"GOOS": "linux", "CGO_ENABLED": "0"},
+	}
+	if p.Name != "ci" || len(p.Steps) != 1 || !reflect.DeepEqual(p.Steps[0], want) {
+		t.Fatalf("expected step %+v, got %+v", want, p.Steps)
+	}
+}
+
+// durations matches the rounded durations of a pipeline summary
+var durations = regexp.MustCompile(`[0-9.]+m?s\b`)
+
+func TestPipeline(t *testing.T) {
+	forceTerm(t)
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "steps.toml")
+	err := os.WriteFile(path, []byte(`
+[[steps]]
+name = "where"
+command = 'test "$(basename "$(pwd)")" = sub && test "$GREETING" = hello'
+dir = "sub"
+env = { GREETING = "hello" }
+
+[[steps]]
+name = "flaky"
+command = "echo flaked; exit 2"
+continue_on_error = true
+
+[[steps]]
+name = "slow"
+args = ["sleep", "5"]
+timeout = "50ms"
+
+[[steps]]
+name = "deploy"
+command = "echo deploying"
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := loadPipeline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	term := scrolltest.New(60, 20)
+	err = pipelineTo(context.Background(), term, windowFlags{size: 3}, p)
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != 1 {
+		t.Fatalf("expected exit status 1, got %v", err)
+	}
+
+	want := []string{
+		"✔ where",
+		"✖ flaky: exit status 2 (continued)",
+		"  flaked",
+		"✖ slow: timed out after Ns",
+		"↷ deploy",
+		"✖ steps: 4 steps, 2 failed, 1 skipped (Ns)",
+		"  ✔ where   Ns",
+		"  ✖ flaky   Ns",
+		"  ✖ slow    Ns",
+		"  ↷ deploy  skipped",
+	}
+	got := durations.ReplaceAllString(strings.Join(term.Lines(), "\n"), "Ns")
+	if got != strings.Join(want, "\n") {
+		t.Fatalf("expected screen\n%s\ngot\n%s", strings.Join(want, "\n"), got)
+	}
+	if got := p.Steps[0]; got.Dir != "sub" || got.Env["GREETING"] != "hello" {
+		t.Fatalf("unexpected step %+v", got)
+	}
+}
+
+func TestLoadPipelineErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"empty.json":   `{"steps": []}`,
+		"unknown.json": `{"steps": [{"name": "a", "command": "true", "retries": 2}]}`,
+		"both.json":    `{"steps": [{"name": "a", "command": "true", "args": ["true"]}]}`,
+		"timeout.toml": "[[steps]]\nname = \"a\"\ncommand = \"true\"\ntimeout = \"soon\"\n",
+		"invalid.toml": "[[steps]]\nname = \"a\"\nname = \"b\"\n",
+	}
+	for name, data := range tests {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadPipeline(path); err == nil {
+			t.Errorf("expected an error loading %s", name)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/louislef299/scroll"
+	"github.com/louislef299/scroll/internal/ansihtml"
+	"github.com/louislef299/scroll/internal/vt"
+)
+
+// replayFormats are the output formats of replay.
+var replayFormats = []string{"ansi", "plain", "json", "html"}
+
+// replay plays back an asciicast recording or the events of a JSONRenderer.
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	speed := fs.Float64("speed", 1, "playback speed, 0 plays back without delays")
+	format := fs.String("format", "ansi", "output `format`: "+strings.Join(replayFormats, ", "))
+	size := fs.Int("n", 5, "scroll window size when re-rendering events")
+	output := fs.String("o", "", "write to `file` instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: scroll replay [flags] <file>")
+		fmt.Fprintln(fs.Output(), "\nPlays back an asciicast recording or JSON Lines events, - reads stdin.")
+		fmt.Fprintln(fs.Output(), "Events can be re-rendered in any format, recordings as ansi, plain or html.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("replay needs exactly one file")
+	}
+	if *speed < 0 {
+		return errors.New("replay speed can't be negative")
+	}
+	if !validFormat(*format) {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	in := io.Reader(os.Stdin)
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	r := bufio.NewReader(in)
+	first, err := r.Peek(1)
+	if err != nil {
+		return fmt.Errorf("empty recording: %w", err)
+	}
+	if first[0] != '{' {
+		return errors.New("recording is neither asciicast nor JSON Lines")
+	}
+	p := &player{speed: *speed, format: *format, size: *size, out: out}
+	return p.play(r)
+}
+
+// validFormat reports whether format is one of the replayFormats.
+func validFormat(format string) bool {
+	for _, f := range replayFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// A player plays back a recording in an output format.
+type player struct {
+	speed  float64
+	format string
+	size   int
+	out    io.Writer
+
+	// The time of the last event played back
+	last time.Duration
+}
+
+// replayEvent is an event written by a JSONRenderer.
+type replayEvent struct {
+	Type       string    `json:"type"`
+	Stage      string    `json:"stage"`
+	Time       time.Time `json:"ts"`
+	Msg        string    `json:"msg"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error"`
+	DurationMS *int64    `json:"duration_ms"`
+}
+
+// play reads the recording line by line, telling asciicast recordings from
+// JSON Lines events by their header.
+func (p *player) play(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<30)
+	if !s.Scan() {
+		return s.Err()
+	}
+
+	var header struct {
+		Version int `json:"version"`
+		Width   int `json:"width"`
+		Height  int `json:"height"`
+	}
+	if err := json.Unmarshal(s.Bytes(), &header); err != nil {
+		return fmt.Errorf("invalid recording: %w", err)
+	}
+	if header.Version == 0 {
+		return p.playEvents(s, s.Bytes())
+	}
+	if header.Version != 2 {
+		return fmt.Errorf("unsupported asciicast version %d", header.Version)
+	}
+	if header.Width < 1 || header.Height < 1 {
+		// like a Recorder without a terminal
+		header.Width, header.Height = 80, 24
+	}
+	return p.playCast(s, header.Width, header.Height)
+}
+
+// playCast writes the output events of an asciicast recording. As plain text
+// or HTML the recording is drawn on a virtual terminal and everything that
+// ends up on it is printed once the recording is over.
+func (p *player) playCast(s *bufio.Scanner, width, height int) error {
+	var term *vt.Terminal
+	switch p.format {
+	case "ansi":
+	case "plain", "html":
+		term = vt.New(width, height)
+	default:
+		return fmt.Errorf("asciicast recordings can't be replayed as %s", p.format)
+	}
+
+	for s.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(s.Bytes(), &event); err != nil {
+			return fmt.Errorf("invalid asciicast event: %w", err)
+		}
+		if len(event) != 3 {
+			return fmt.Errorf("invalid asciicast event %s", s.Text())
+		}
+		at, ok := event[0].(float64)
+		kind, _ := event[1].(string)
+		data, _ := event[2].(string)
+		if !ok || kind != "o" {
+			continue
+		}
+
+		if term != nil {
+			term.Write([]byte(data))
+			continue
+		}
+		p.wait(time.Duration(at * float64(time.Second)))
+		if _, err := io.WriteString(p.out, data); err != nil {
+			return err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	switch {
+	case term == nil:
+	case p.format == "html":
+		return castHTML(p.out, term)
+	default:
+		for _, l := range term.Lines() {
+			if _, err := fmt.Fprintln(p.out, l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// castHTML writes everything on the virtual terminal as an HTML page, with
+// the colors of the cells converted to CSS.
+func castHTML(w io.Writer, term *vt.Terminal) error {
+	var sb strings.Builder
+	sb.WriteString(ansihtml.Head)
+	sb.WriteString("<pre>")
+	for _, row := range term.Cells() {
+		sb.WriteString(ansihtml.HTML(sgrRow(row), ansihtml.Style{}))
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("</pre>\n</body>\n</html>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// sgrRow encodes the cells of a row as text with SGR escape sequences,
+// leaving out trailing blanks.
+func sgrRow(row []vt.Cell) string {
+	end := len(row)
+	for end > 0 && row[end-1].Rune == ' ' && row[end-1].Attr == (vt.Attr{}) {
+		end--
+	}
+
+	var sb strings.Builder
+	var attr vt.Attr
+	for _, c := range row[:end] {
+		if c.Attr != attr {
+			sb.WriteString(sgr(c.Attr))
+			attr = c.Attr
+		}
+		sb.WriteRune(c.Rune)
+	}
+	return sb.String()
+}
+
+// sgr returns the SGR escape sequence that sets the attribute from scratch.
+// Reversed colors are swapped, with the default colors taken as white on
+// black.
+func sgr(a vt.Attr) string {
+	params := []string{"0"}
+	fg, bg := a.FG, a.BG
+	if a.Reverse {
+		if fg == "" {
+			fg = "37"
+		}
+		if bg == "" {
+			bg = "40"
+		}
+		fg, bg = swapLayer(bg), swapLayer(fg)
+	}
+	if fg != "" {
+		params = append(params, fg)
+	}
+	if bg != "" {
+		params = append(params, bg)
+	}
+	for _, f := range []struct {
+		on    bool
+		param string
+	}{{a.Bold, "1"}, {a.Faint, "2"}, {a.Italic, "3"}, {a.Underline, "4"}} {
+		if f.on {
+			params = append(params, f.param)
+		}
+	}
+	return "\033[" + strings.Join(params, ";") + "m"
+}
+
+// swapLayer turns the SGR parameters of a foreground color into those of
+// the same background color and back.
+func swapLayer(c string) string {
+	switch {
+	case strings.HasPrefix(c, "38;"):
+		return "48;" + c[3:]
+	case strings.HasPrefix(c, "48;"):
+		return "38;" + c[3:]
+	}
+	n, err := strconv.Atoi(c)
+	if err != nil {
+		return c
+	}
+	switch {
+	case n >= 30 && n <= 37, n >= 90 && n <= 97:
+		n += 10
+	case n >= 40 && n <= 47, n >= 100 && n <= 107:
+		n -= 10
+	}
+	return strconv.Itoa(n)
+}
+
+// playEvents re-renders JSON Lines events with a new Buffer, starting with
+// the already scanned first event. Lines and stages keep the times they were
+// recorded at, and only ANSI output is played back in real time.
+func (p *player) playEvents(s *bufio.Scanner, first []byte) error {
+	var events []replayEvent
+	line := bytes.Clone(first)
+	for {
+		var e replayEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("invalid event: %w", err)
+		}
+		events = append(events, e)
+		if !s.Scan() {
+			break
+		}
+		line = s.Bytes()
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var buff *scroll.Buffer
+	switch p.format {
+	case "ansi":
+		buff = scroll.New(ctx, p.out, p.size)
+		buff.SetIsTerm(true)
+	case "plain":
+		buff = scroll.New(ctx, p.out, p.size)
+		buff.SetIsTerm(false)
+		buff.SetCI(scroll.NoCI)
+	case "json":
+		buff = scroll.NewWithRenderer(ctx, scroll.NewJSONRenderer(p.out), p.size)
+	case "html":
+		buff = scroll.NewWithRenderer(ctx, scroll.NewJSONRenderer(io.Discard), p.size)
+	}
+
+	start := events[0].Time
+	now := recordingStart(events)
+	buff.SetClock(func() time.Time { return now })
+	var stages []*scroll.Stage
+	for _, e := range events {
+		now = e.Time
+		if p.format == "ansi" {
+			p.wait(e.Time.Sub(start))
+		}
+		stages = replayOne(buff, stages, e)
+	}
+
+	if p.format == "html" {
+		if err := buff.ExportHTML(p.out); err != nil {
+			buff.Close()
+			return err
+		}
+	}
+	return buff.Close()
+}
+
+// recordingStart returns the time the recorded Buffer started, which is
+// before the first event if the first unnamed stage lasted longer.
+func recordingStart(events []replayEvent) time.Time {
+	start := events[0].Time
+	for _, e := range events {
+		if e.Type == "stage_end" && e.Stage == "" {
+			if e.DurationMS != nil {
+				if began := e.Time.Add(-time.Duration(*e.DurationMS) * time.Millisecond); began.Before(start) {
+					start = began
+				}
+			}
+			break
+		}
+	}
+	return start
+}
+
+// replayOne applies an event to the Buffer and returns the stages that are
+// still open, innermost last.
+func replayOne(buff *scroll.Buffer, stages []*scroll.Stage, e replayEvent) []*scroll.Stage {
+	switch e.Type {
+	case "line":
+		if len(stages) == 0 {
+			buff.Printf("%s", e.Msg)
+		} else {
+			stages[len(stages)-1].Printf("%s", e.Msg)
+		}
+	case "stage_begin":
+		if len(stages) == 0 {
+			stages = append(stages, buff.BeginStage(e.Stage))
+		} else {
+			stages = append(stages, stages[len(stages)-1].BeginStage(e.Stage))
+		}
+	case "stage_end":
+		if e.Stage == "" {
+			finish(buff, nil, e)
+			return nil
+		}
+		for i := len(stages) - 1; i >= 0; i-- {
+			if stages[i].Name() == e.Stage {
+				finish(buff, stages[i], e)
+				return stages[:i]
+			}
+		}
+	}
+	return stages
+}
+
+// finish finishes the stage, or the current stage of the Buffer when it is
+// nil, with the outcome of the event.
+func finish(buff *scroll.Buffer, st *scroll.Stage, e replayEvent) {
+	switch e.Status {
+	case "ok":
+		if st == nil {
+			buff.StageOK("%s", e.Msg)
+		} else {
+			st.OK("%s", e.Msg)
+		}
+	case "fail":
+		msg := e.Error
+		if msg == "" {
+			msg = e.Msg
+		}
+		if st == nil {
+			buff.StageFail(errors.New(msg))
+		} else {
+			st.Fail(errors.New(msg))
+		}
+	case "warn":
+		if st == nil {
+			buff.StageWarn("%s", e.Msg)
+		} else {
+			st.Warn("%s", e.Msg)
+		}
+	case "skip":
+		if st == nil {
+			buff.StageSkip("%s", e.Msg)
+		} else {
+			st.Skip("%s", e.Msg)
+		}
+	default:
+		if st == nil {
+			buff.NewStage("%s", e.Msg)
+		} else {
+			st.End("%s", e.Msg)
+		}
+	}
+}
+
+// wait sleeps until the event at the offset from the start of the recording
+// is due at the playback speed.
+func (p *player) wait(at time.Duration) {
+	if p.speed == 0 || at <= p.last {
+		return
+	}
+	time.Sleep(time.Duration(float64(at-p.last) / p.speed))
+	p.last = at
+}
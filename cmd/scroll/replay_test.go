@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/louislef299/scroll"
+)
+
+// record writes a session to a file with the renderer made for out.
+func record(t *testing.T, name string, newBuffer func(out *bytes.Buffer) *scroll.Buffer) string {
+	t.Helper()
+	var out bytes.Buffer
+	buff := newBuffer(&out)
+	build := buff.BeginStage("build")
+	build.Printf("compiling")
+	build.OK("built")
+	buff.Printf("test 0")
+	buff.StageFail(errors.New("tests failed"))
+	if err := buff.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplayEvents(t *testing.T) {
+	events := record(t, "session.jsonl", func(out *bytes.Buffer) *scroll.Buffer {
+		return scroll.NewWithRenderer(context.Background(), scroll.NewJSONRenderer(out), 2)
+	})
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "plain",
+			want:   "compiling\n✔ built\ntest 0\n✖ tests failed\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			if err := replay([]string{"-speed", "0", "-format", tt.format, "-o", out, events}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("expected output\n%q\ngot\n%q", tt.want, got)
+			}
+		})
+	}
+
+	// replaying as JSON gives back the same events
+	out := filepath.Join(t.TempDir(), "out")
+	if err := replay([]string{"-speed", "0", "-format", "json", "-o", out, events}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(got, []byte("\n")); n != 5 {
+		t.Fatalf("expected 5 events, got %d\n%s", n, got)
+	}
+}
+
+func TestReplayCast(t *testing.T) {
+	cast := record(t, "session.cast", func(out *bytes.Buffer) *scroll.Buffer {
+		rec := scroll.NewRecorder(&bytes.Buffer{}, out)
+		rec.SetSize(40, 10)
+		buff := scroll.New(context.Background(), rec, 2)
+		buff.SetIsTerm(true)
+		buff.SetWidth(40)
+		return buff
+	})
+
+	out := filepath.Join(t.TempDir(), "out")
+	if err := replay([]string{"-speed", "0", "-format", "plain", "-o", out, cast}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "✔ built\n✖ tests failed\n  test 0\n"
+	if string(got) != want {
+		t.Fatalf("expected output\n%q\ngot\n%q", want, got)
+	}
+
+	if err := replay([]string{"-speed", "0", "-format", "html", "-o", out, cast}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = os.ReadFile(out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<span style="color:#00cd00">✔ built</span>`,
+		`<span style="color:#cd0000">✖ tests failed</span>`,
+		"\n  test 0\n",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Fatalf("expected the page to contain %q, got\n%s", want, got)
+		}
+	}
+
+	if err := replay([]string{"-format", "json", cast}); err == nil {
+		t.Fatal("expected an error replaying a recording as json")
+	}
+}
+
+func TestReplayCastSize(t *testing.T) {
+	cast := filepath.Join(t.TempDir(), "session.cast")
+	data := `{"version":2,"width":0,"height":0}` + "\n" + `[0.1,"o","hello\r\n"]` + "\n"
+	if err := os.WriteFile(cast, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	if err := replay([]string{"-speed", "0", "-format", "plain", "-o", out, cast}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(out); err != nil || string(got) != "hello\n" {
+		t.Fatalf("expected the recording on an 80x24 terminal, got %q, %v", got, err)
+	}
+}
+
+func TestReplayEventTimes(t *testing.T) {
+	events := filepath.Join(t.TempDir(), "session.jsonl")
+	data := `{"type":"line","ts":"2024-01-01T10:00:00Z","msg":"hello"}
+{"type":"stage_end","ts":"2024-01-01T10:00:42Z","msg":"done","status":"ok","duration_ms":45000,"lines":1}
+{"type":"stage_begin","stage":"build","ts":"2024-01-01T10:00:43Z"}
+{"type":"line","stage":"build","ts":"2024-01-01T10:00:44Z","msg":"compiling"}
+{"type":"stage_end","stage":"build","ts":"2024-01-01T10:01:25Z","msg":"built","status":"ok","duration_ms":42000,"lines":1}
+`
+	if err := os.WriteFile(events, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// only ANSI output is played back in real time, so this doesn't wait
+	out := filepath.Join(t.TempDir(), "out")
+	if err := replay([]string{"-format", "json", "-o", out, events}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"ts":"2024-01-01T10:00:00Z","msg":"hello"`,
+		`"msg":"done","status":"ok","duration_ms":45000`,
+		`"ts":"2024-01-01T10:00:44Z","msg":"compiling"`,
+		`"msg":"built","status":"ok","duration_ms":42000`,
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Fatalf("expected the events to contain %q, got\n%s", want, got)
+		}
+	}
+
+	if err := replay([]string{"-format", "html", "-o", out, events}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = os.ReadFile(out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "10:00:43 &middot; 42.0s") {
+		t.Fatalf("expected the recorded start and duration of the stage, got\n%s", got)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runCmd runs a command and shows its output in a scroll window.
+func runCmd(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	var wf windowFlags
+	wf.register(fs)
+	wf.registerOutcome(fs, "the title")
+	title := fs.String("title", "", "`title` of the stage, defaults to the command line")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: scroll run [flags] -- <command> [args]")
+		fmt.Fprintln(fs.Output(), "\nRuns the command and shows its stdout and stderr in a scroll window. When the")
+		fmt.Fprintln(fs.Output(), "command fails, every line is dumped and scroll exits with its exit status.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("run needs a command")
+	}
+	return runTo(context.Background(), os.Stdout, wf, *title, fs.Args())
+}
+
+// runTo runs the command line argv with its output in a scroll window
+// written to w. The window collapses to the title of the stage, or to the
+// reason the command failed followed by every line it printed.
+func runTo(ctx context.Context, w io.Writer, wf windowFlags, title string, argv []string) error {
+	buff, fail, err := wf.buffer(ctx, w)
+	if err != nil {
+		return err
+	}
+	defer buff.Close()
+	if title == "" {
+		title = strings.Join(argv, " ")
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	if err := buff.Exec(ctx, cmd); err != nil {
+		buff.StageFail(fmt.Errorf("%s: %w", title, err))
+		return &exitError{code: exitCode(cmd, err)}
+	}
+	if matched := firstMatch(buff.StageLog(), fail); matched != "" {
+		buff.StageFail(fmt.Errorf("%s: %s", title, matched))
+		return &exitError{code: 1}
+	}
+
+	msg := wf.message
+	if msg == "" {
+		msg = title
+	}
+	buff.StageOK("%s", msg)
+	return buff.Close()
+}
+
+// exitCode returns the exit status of scroll for a command that failed with
+// err. Commands that could not be started exit with 127 like in a shell.
+func exitCode(cmd *exec.Cmd, err error) int {
+	var exit *exec.ExitError
+	switch {
+	case cmd.Process == nil:
+		return 127
+	case errors.As(err, &exit) && exit.ExitCode() > 0:
+		return exit.ExitCode()
+	}
+	return 1
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+func TestRun(t *testing.T) {
+	forceTerm(t)
+	tests := []struct {
+		name  string
+		title string
+		fail  string
+		argv  []string
+		want  []string
+		code  int
+	}{
+		{
+			name:  "success",
+			title: "Build",
+			argv:  []string{"sh", "-c", "echo one; echo two >&2"},
+			want:  []string{"✔ Build"},
+		},
+		{
+			name: "failure",
+			argv: []string{"sh", "-c", "echo one; echo two >&2; exit 3"},
+			want: []string{"✖ sh -c echo one; echo two >&2; exit 3: exit status 3", "  one", "  two"},
+			code: 3,
+		},
+		{
+			name:  "fail pattern",
+			title: "Build",
+			fail:  "^error",
+			argv:  []string{"sh", "-c", "echo one; echo error: two; echo error: three"},
+			want:  []string{"✖ Build: error: two", "  error: three", "  error: two", "  one"},
+			code:  1,
+		},
+		{
+			name:  "not found",
+			title: "Build",
+			argv:  []string{"scroll-does-not-exist"},
+			want:  []string{`✖ Build: exec: "scroll-does-not-exist": executable file not found in $PATH`},
+			code:  127,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := scrolltest.New(80, 10)
+			err := runTo(context.Background(), term, windowFlags{size: 5, failPattern: tt.fail}, tt.title, tt.argv)
+
+			var exit *exitError
+			switch {
+			case tt.code == 0 && err != nil:
+				t.Fatal(err)
+			case tt.code != 0 && (!errors.As(err, &exit) || exit.code != tt.code):
+				t.Fatalf("expected exit status %d, got %v", tt.code, err)
+			}
+			// stdout and stderr are read apart, so the dumped lines are sorted
+			got := term.Lines()
+			if len(got) > 1 {
+				sort.Strings(got[1:])
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("expected screen\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+			for i, l := range term.Lines() {
+				if l == "  two" && term.Row(i)[2].Attr.FG != "31" {
+					t.Fatalf("expected stderr in red, got %q", term.Row(i)[2].Attr.FG)
+				}
+			}
+		})
+	}
+}
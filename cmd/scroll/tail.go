@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/louislef299/scroll"
+)
+
+// The most bytes read from the end of a file for its initial lines
+const tailWindow = 64 << 10
+
+// tail follows files in scroll windows stacked on the terminal.
+func tail(args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ContinueOnError)
+	var wf windowFlags
+	wf.register(fs)
+	interval := fs.Duration("interval", 250*time.Millisecond, "how often the files are checked for changes")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: scroll tail [flags] <file>...")
+		fmt.Fprintln(fs.Output(), "\nFollows the files like tail -F, each in its own scroll window with its name")
+		fmt.Fprintln(fs.Output(), "above it, until interrupted. Files that are truncated, replaced or don't")
+		fmt.Fprintln(fs.Output(), "exist yet are followed as well.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("tail needs at least one file")
+	}
+	if wf.size < 1 {
+		return fmt.Errorf("invalid scroll window size %d", wf.size)
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval %s", *interval)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return tailTo(ctx, os.Stdout, wf, fs.Args(), *interval)
+}
+
+// tailTo follows the files in the scroll windows of a Manager writing to w
+// until the context is done. Each window is a stage named after its file.
+func tailTo(ctx context.Context, w io.Writer, wf windowFlags, paths []string, interval time.Duration) error {
+	m := scroll.NewManager(w)
+	var wg sync.WaitGroup
+	for _, path := range paths {
+		// the Buffer outlives ctx to finish its stage
+		buff := m.New(context.Background(), wf.size)
+		buff.SetHeader(true)
+		// files are followed for as long as tail runs, so only the lines
+		// of the window are kept
+		buff.SetHistoryLimit(wf.size)
+		prefix := wf.prefix
+		if prefix == "" && !scroll.IsTerm && len(paths) > 1 {
+			// tell the lines of the files apart without windows
+			prefix = path + ":"
+		}
+		buff.SetPrefix(prefix)
+		st := buff.BeginStage(path)
+
+		wg.Add(1)
+		go func(t *tailer) {
+			defer wg.Done()
+			t.follow(ctx, st, interval)
+			st.End("")
+			buff.Close()
+		}(&tailer{path: path, lines: wf.size})
+	}
+	wg.Wait()
+	return nil
+}
+
+// A tailer follows a file like tail -F.
+type tailer struct {
+	path string
+
+	// The number of lines printed from the end of the file when it is
+	// first opened
+	lines int
+
+	// The open file, its info and how much of it has been read
+	file   *os.File
+	info   os.FileInfo
+	offset int64
+
+	// The incomplete last line read so far
+	partial []byte
+
+	// States whether the file was checked before
+	polled bool
+}
+
+// follow prints the lines of the file to the stage until the context is done.
+func (t *tailer) follow(ctx context.Context, st *scroll.Stage, interval time.Duration) {
+	defer t.close()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, l := range t.poll() {
+			st.Printf("%s", l)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll returns the lines added to the file since the last poll, along with
+// notes on files being truncated, replaced or removed.
+func (t *tailer) poll() []string {
+	first := !t.polled
+	t.polled = true
+
+	info, err := os.Stat(t.path)
+	if err != nil {
+		if t.file == nil {
+			if first {
+				return []string{t.noteErr(err)}
+			}
+			return nil
+		}
+		lines := t.read()
+		t.close()
+		return append(lines, t.note("file removed"))
+	}
+
+	var lines []string
+	switch {
+	case t.file == nil:
+		if !first {
+			lines = append(lines, t.note("file appeared"))
+		}
+		if err := t.open(info, first); err != nil {
+			return append(lines, t.noteErr(err))
+		}
+	case !os.SameFile(info, t.info):
+		// finish the old file before following the new one
+		lines = append(t.read(), t.note("file replaced, following new file"))
+		t.close()
+		if err := t.open(info, false); err != nil {
+			return append(lines, t.noteErr(err))
+		}
+	case info.Size() < t.offset:
+		lines = append(lines, t.note("file truncated"))
+		t.offset = 0
+		t.partial = nil
+	}
+	t.info = info
+	return append(lines, t.read()...)
+}
+
+// open opens the file. A file that existed before following it starts at its
+// last lines, any other file at its beginning.
+func (t *tailer) open(info os.FileInfo, last bool) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	t.file, t.info, t.offset, t.partial = f, info, 0, nil
+	if !last {
+		return nil
+	}
+
+	start := info.Size() - tailWindow
+	if start < 0 {
+		start = 0
+	}
+	data := make([]byte, info.Size()-start)
+	n, err := f.ReadAt(data, start)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	data = data[:n]
+	if start > 0 {
+		// drop the line cut off by the start of the window
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+
+	// keep the last lines and the incomplete last line
+	end := bytes.LastIndexByte(data, '\n') + 1
+	complete := data[:end]
+	for i, n := len(complete)-1, 0; i >= 0; i-- {
+		if complete[i] == '\n' {
+			n++
+			if n > t.lines {
+				complete = complete[i+1:]
+				break
+			}
+		}
+	}
+	t.offset = info.Size() - int64(len(data)-end) - int64(len(complete))
+	return nil
+}
+
+// read returns the complete lines written to the file since the last read.
+func (t *tailer) read() []string {
+	if t.file == nil {
+		return nil
+	}
+	data, err := io.ReadAll(io.NewSectionReader(t.file, t.offset, 1<<62))
+	t.offset += int64(len(data))
+	if err != nil {
+		return []string{t.noteErr(err)}
+	}
+
+	data = append(t.partial, data...)
+	end := bytes.LastIndexByte(data, '\n') + 1
+	t.partial = append([]byte(nil), data[end:]...)
+	if end == 0 {
+		return nil
+	}
+	lines := strings.Split(string(data[:end-1]), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines
+}
+
+// close closes the file.
+func (t *tailer) close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+// note returns a line describing something that happened to the file.
+func (t *tailer) note(msg string) string {
+	return fmt.Sprintf("scroll: %s: %s", t.path, msg)
+}
+
+// noteErr returns a line describing an error reading the file.
+func (t *tailer) noteErr(err error) string {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return t.note(err.Error())
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+func TestTailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	write := func(flag int, data string) {
+		t.Helper()
+		f, err := os.OpenFile(path, flag|os.O_WRONLY|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	note := func(msg string) string {
+		return "scroll: " + path + ": " + msg
+	}
+
+	tl := &tailer{path: path, lines: 2}
+	steps := []struct {
+		name   string
+		change func()
+		want   []string
+	}{
+		{"missing", func() {}, []string{note("no such file or directory")}},
+		{"appeared", func() { write(os.O_APPEND, "one\ntwo\n") }, []string{note("file appeared"), "one", "two"}},
+		{"append", func() { write(os.O_APPEND, "three\nfo") }, []string{"three"}},
+		{"partial", func() { write(os.O_APPEND, "ur\r\n") }, []string{"four"}},
+		{"idle", func() {}, nil},
+		{"truncated", func() { write(os.O_TRUNC, "new\n") }, []string{note("file truncated"), "new"}},
+		{"replaced", func() {
+			write(os.O_APPEND, "old\n")
+			if err := os.Rename(path, path+".1"); err != nil {
+				t.Fatal(err)
+			}
+			write(os.O_TRUNC, "rotated\n")
+		}, []string{"old", note("file replaced, following new file"), "rotated"}},
+		{"removed", func() {
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+		}, []string{note("file removed")}},
+	}
+	for _, s := range steps {
+		s.change()
+		if got := tl.poll(); !reflect.DeepEqual(got, s.want) {
+			t.Fatalf("%s: expected lines %q, got %q", s.name, s.want, got)
+		}
+	}
+	tl.close()
+}
+
+func TestTailerLastLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\nfour\nfi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tl := &tailer{path: path, lines: 2}
+	defer tl.close()
+	if got, want := tl.poll(), []string{"three", "four"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+}
+
+func TestTail(t *testing.T) {
+	forceTerm(t)
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	for _, path := range []string{a, b} {
+		if err := os.WriteFile(path, []byte("start\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	term := scrolltest.New(80, 20)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := tailTo(ctx, term, windowFlags{size: 3}, []string{a, b}, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// the windows finish in any order
+	got := term.Lines()
+	sort.Strings(got)
+	if len(got) != 2 || !strings.HasPrefix(got[0], "✔ "+a) || !strings.HasPrefix(got[1], "✔ "+b) {
+		t.Fatalf("expected a finished stage per file, got\n%s", strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/louislef299/scroll"
+)
+
+// watch reruns a command periodically with its latest output in a scroll
+// window.
+func watch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	var wf windowFlags
+	wf.register(fs)
+	interval := fs.Duration("i", 2*time.Second, "`interval` between the end of a run and the next")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: scroll watch [flags] -- <command> [args]")
+		fmt.Fprintln(fs.Output(), "\nReruns the command until interrupted. The output of the latest run replaces")
+		fmt.Fprintln(fs.Output(), "the previous one in the scroll window, below a title with the time the run")
+		fmt.Fprintln(fs.Output(), "ended and its exit status.")
+		fmt.Fprintln(fs.Output())
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("watch needs a command")
+	}
+	if wf.size < 1 {
+		return fmt.Errorf("invalid scroll window size %d", wf.size)
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval %s", *interval)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return watchTo(ctx, os.Stdout, wf, *interval, fs.Args())
+}
+
+// watchTo reruns the command line argv with its output in a scroll window
+// written to w until the context is done. The window holds a title line
+// followed by the last lines of the latest run, and collapses to the title of
+// the latest run at the end.
+func watchTo(ctx context.Context, w io.Writer, wf windowFlags, interval time.Duration, argv []string) error {
+	// the Buffer outlives ctx to finish its stage, and has a row for the title
+	buff := scroll.New(context.Background(), w, wf.size+1)
+	buff.SetPrefix(wf.prefix)
+	// every run replaces the previous one, so only the window is kept
+	buff.SetHistoryLimit(wf.size + 1)
+	defer buff.Close()
+
+	var title string
+	for ctx.Err() == nil {
+		lines, status := capture(ctx, argv)
+		if ctx.Err() != nil {
+			break
+		}
+		title = fmt.Sprintf("Every %s: %s  %s  %s", interval, strings.Join(argv, " "),
+			time.Now().Format("15:04:05"), status)
+
+		buff.EraseBuffer()
+		buff.Printf("%s", title)
+		for _, l := range lastRows(lines, wf.prefix, 0, wf.size+1-rows(title, wf.prefix, 0)) {
+			buff.Printf("%s", l)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+
+	if title == "" {
+		buff.EraseBuffer()
+	} else {
+		buff.NewStage("%s", title)
+	}
+	return buff.Close()
+}
+
+// lastRows returns the last lines that fit in n rows of a scroll window of
+// the width, zero detecting the width like a Buffer does, with the prefix.
+func lastRows(lines []string, prefix string, width, n int) []string {
+	i := len(lines)
+	for i > 0 {
+		r := rows(lines[i-1], prefix, width)
+		if r > n {
+			break
+		}
+		n -= r
+		i--
+	}
+	return lines[i:]
+}
+
+// rows returns the number of rows the line wraps to in a scroll window.
+func rows(line, prefix string, width int) int {
+	win := scroll.Window{
+		Lines:  []scroll.Line{{Text: line}},
+		Size:   math.MaxInt32,
+		Width:  width,
+		Prefix: prefix,
+	}
+	return len(win.Rows())
+}
+
+// capture runs the command line argv and returns the lines of its stdout and
+// stderr along with a description of how it exited.
+func capture(ctx context.Context, argv []string) ([]string, string) {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	// don't wait for children of the command that keep its output open
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+
+	var lines []string
+	s := bufio.NewScanner(bytes.NewReader(out))
+	s.Buffer(nil, len(out)+1)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+
+	var exit *exec.ExitError
+	switch {
+	case err == nil, errors.Is(err, exec.ErrWaitDelay):
+		return lines, "exit 0"
+	case errors.As(err, &exit) && exit.ExitCode() >= 0:
+		return lines, fmt.Sprintf("exit %d", exit.ExitCode())
+	}
+	return lines, err.Error()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+func TestWatch(t *testing.T) {
+	forceTerm(t)
+	count := filepath.Join(t.TempDir(), "count")
+	script := `n=$(cat "$1" 2>/dev/null || echo 0); n=$((n+1)); echo $n > "$1"; echo run $n; exit $n`
+	argv := []string{"sh", "-c", script, "sh", count}
+
+	term := scrolltest.New(200, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go func() {
+		// stop watching once the command ran a few times
+		for ctx.Err() == nil {
+			data, _ := os.ReadFile(count)
+			if n, _ := strconv.Atoi(strings.TrimSpace(string(data))); n >= 3 {
+				cancel()
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	if err := watchTo(ctx, term, windowFlags{size: 3}, 20*time.Millisecond, argv); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs < 3 {
+		t.Fatalf("expected the command to run at least 3 times, got %d", runs)
+	}
+
+	// every run replaced the previous one and the window collapsed to the
+	// title of one of the last runs
+	got := term.Lines()
+	if len(got) != 1 || !strings.HasPrefix(got[0], "Every 20ms: sh -c") || !strings.Contains(got[0], "  exit ") {
+		t.Fatalf("expected the title of the last run, got\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestLastRows(t *testing.T) {
+	long := strings.Repeat("x", 15)
+	lines := []string{"a", long, "b"}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{n: 4, want: lines},
+		{n: 3, want: []string{long, "b"}},
+		{n: 2, want: []string{"b"}},
+		{n: 0, want: []string{}},
+	}
+	for _, tt := range tests {
+		if got := lastRows(lines, "", 10, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expected %q in %d rows, got %q", tt.want, tt.n, got)
+		}
+	}
+	if got := rows(long, "=>", 10); got != 2 {
+		t.Fatalf("expected the prefixed line to take 2 rows, got %d", got)
+	}
+}
@@ -3,6 +3,7 @@ module github.com/louislef299/scroll
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/fatih/color v1.15.0
 	golang.org/x/sys v0.13.0
 	golang.org/x/term v0.13.0
@@ -0,0 +1,211 @@
+package scroll
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// goTestEvent is an event written by go test -json.
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// goTestLine is a line of output of a package and the test that wrote it,
+// empty for output of the package itself.
+type goTestLine struct {
+	test string
+	text string
+}
+
+// goTestPackage is the state of a package in go test -json output.
+type goTestPackage struct {
+	name string
+
+	// Every line of output, how many of them have been printed and the
+	// incomplete last line. Lines are held back while another package is
+	// shown.
+	lines   []goTestLine
+	printed int
+	partial string
+
+	// The tests that failed
+	failed map[string]bool
+
+	// The final event of the package once it is done
+	end *goTestEvent
+}
+
+// GoTest reads the output of go test -json from r and shows every package as
+// a stage of the Buffer, with the output of its running tests scrolling in
+// the window. Passing packages collapse to "ok pkg (1.2s)" and failing
+// packages are followed by the full output of their failing tests and the
+// output of the package itself. Packages tested in parallel are shown one at
+// a time.
+//
+// Lines that are not JSON, such as build errors, are printed as they are. The
+// ok is false if any package failed.
+func GoTest(b *Buffer, r io.Reader) (ok bool, err error) {
+	g := &goTest{b: b, packages: map[string]*goTestPackage{}, ok: true}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<30)
+	for s.Scan() {
+		var e goTestEvent
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil || e.Action == "" {
+			g.print(s.Text())
+			continue
+		}
+		g.event(e)
+	}
+
+	// packages without a result were cut off
+	for len(g.order) > 0 || g.current != nil {
+		if g.current == nil {
+			g.next()
+			continue
+		}
+		if g.current.end == nil {
+			g.current.end = &goTestEvent{Action: "fail", Package: g.current.name}
+		}
+		g.finish()
+	}
+	return g.ok, s.Err()
+}
+
+// goTest shows go test -json events on a Buffer.
+type goTest struct {
+	b *Buffer
+
+	packages map[string]*goTestPackage
+
+	// The packages waiting to be shown, in the order they started
+	order []*goTestPackage
+
+	// The package shown and its stage
+	current *goTestPackage
+	stage   *Stage
+
+	ok bool
+}
+
+// event handles a single event.
+func (g *goTest) event(e goTestEvent) {
+	if e.Package == "" {
+		if e.Output != "" {
+			g.print(strings.TrimSuffix(e.Output, "\n"))
+		}
+		return
+	}
+
+	p, seen := g.packages[e.Package]
+	if !seen {
+		p = &goTestPackage{name: e.Package, failed: map[string]bool{}}
+		g.packages[e.Package] = p
+		g.order = append(g.order, p)
+	}
+	if g.current == nil {
+		g.next()
+	}
+
+	switch {
+	case e.Action == "output":
+		g.output(p, e.Test, e.Output)
+	case e.Action == "fail" && e.Test != "":
+		p.failed[e.Test] = true
+	case e.Test == "" && (e.Action == "pass" || e.Action == "fail" || e.Action == "skip"):
+		p.end = &e
+		for g.current != nil && g.current.end != nil {
+			g.finish()
+			g.next()
+		}
+	}
+}
+
+// output adds output of the test to the package, printing complete lines if
+// it is shown. An incomplete line belongs to the test that completes it.
+func (g *goTest) output(p *goTestPackage, test, out string) {
+	out = p.partial + out
+	lines := strings.Split(out, "\n")
+	p.partial = lines[len(lines)-1]
+	for _, l := range lines[:len(lines)-1] {
+		p.lines = append(p.lines, goTestLine{test: test, text: l})
+	}
+	if p == g.current {
+		g.flush()
+	}
+}
+
+// flush prints the lines of the shown package that haven't been printed.
+func (g *goTest) flush() {
+	p := g.current
+	for _, l := range p.lines[p.printed:] {
+		g.stage.Printf("%s", l.text)
+	}
+	p.printed = len(p.lines)
+}
+
+// print prints a line to the shown package or the Buffer.
+func (g *goTest) print(l string) {
+	if g.current != nil {
+		g.current.lines = append(g.current.lines, goTestLine{text: l})
+		g.flush()
+		return
+	}
+	g.b.Printf("%s", l)
+}
+
+// next shows the first waiting package along with the output it received
+// so far.
+func (g *goTest) next() {
+	if len(g.order) == 0 {
+		return
+	}
+	p := g.order[0]
+	g.order = g.order[1:]
+	g.current = p
+	g.stage = g.b.BeginStage(p.name)
+	g.flush()
+}
+
+// finish finishes the stage of the shown package with its result.
+func (g *goTest) finish() {
+	p, st := g.current, g.stage
+	if p.partial != "" {
+		p.lines = append(p.lines, goTestLine{text: p.partial})
+		p.partial = ""
+		g.flush()
+	}
+	elapsed := formatDuration(time.Duration(p.end.Elapsed * float64(time.Second)))
+
+	switch p.end.Action {
+	case "pass":
+		st.End("ok %s (%s)", p.name, elapsed)
+	case "skip":
+		st.Skip("%s [no test files]", p.name)
+	default:
+		g.ok = false
+		g.fail(st, fmt.Errorf("FAIL %s (%s)", p.name, elapsed))
+	}
+	p.lines = nil
+	g.current, g.stage = nil, nil
+}
+
+// fail finishes the stage of the shown package as failed, followed by the
+// output of its failed tests and of the package itself.
+func (g *goTest) fail(st *Stage, err error) {
+	dump := newLog(g.b.budget, 0)
+	defer dump.Close()
+	for _, l := range g.current.lines {
+		if l.test == "" || g.current.failed[l.test] {
+			dump.append(Line{Text: l.text, Time: time.Now()})
+		}
+	}
+	st.failWith(err, dump)
+}
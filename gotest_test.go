@@ -0,0 +1,60 @@
+package scroll_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/louislef299/scroll"
+)
+
+func TestGoTest(t *testing.T) {
+	buff, term := newTermBuffer(t, 60, 20, 3)
+
+	input := `{"Action":"start","Package":"example.com/a"}
+{"Action":"run","Package":"example.com/a","Test":"TestA"}
+{"Action":"output","Package":"example.com/a","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"start","Package":"example.com/b"}
+{"Action":"output","Package":"example.com/b","Test":"TestB","Output":"=== RUN   TestB\n"}
+{"Action":"output","Package":"example.com/b","Test":"TestB","Output":"    b_test.go:9: "}
+{"Action":"output","Package":"example.com/b","Test":"TestB","Output":"want 1, got 2\n"}
+{"Action":"output","Package":"example.com/a","Test":"TestA","Output":"--- PASS: TestA (0.00s)\n"}
+{"Action":"pass","Package":"example.com/a","Test":"TestA","Elapsed":0}
+{"Action":"output","Package":"example.com/b","Test":"TestB","Output":"--- FAIL: TestB (0.00s)\n"}
+{"Action":"fail","Package":"example.com/b","Test":"TestB","Elapsed":0}
+{"Action":"output","Package":"example.com/b","Test":"TestC","Output":"=== RUN   TestC\n"}
+{"Action":"output","Package":"example.com/b","Test":"TestC","Output":"--- PASS: TestC (0.00s)\n"}
+{"Action":"pass","Package":"example.com/b","Test":"TestC","Elapsed":0}
+{"Action":"output","Package":"example.com/a","Output":"ok  \texample.com/a\t0.512s\n"}
+{"Action":"pass","Package":"example.com/a","Elapsed":0.512}
+# example.com/c
+{"Action":"output","Package":"example.com/b","Output":"FAIL\texample.com/b\t1.2s\n"}
+{"Action":"fail","Package":"example.com/b","Elapsed":1.2}
+{"Action":"output","Package":"example.com/c","Output":"?   \texample.com/c\t[no test files]\n"}
+{"Action":"skip","Package":"example.com/c","Elapsed":0}
+{"Action":"start","Package":"example.com/d"}
+{"Action":"output","Package":"example.com/d","Output":"panic: cut off\n"}
+`
+	ok, err := scroll.GoTest(buff, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("expected a failed package")
+	}
+
+	want := []string{
+		"ok example.com/a (0.5s)",
+		"✖ FAIL example.com/b (1.2s)",
+		"  === RUN   TestB",
+		"  b_test.go:9: want 1, got 2",
+		"  --- FAIL: TestB (0.00s)",
+		"  # example.com/c",
+		"  FAIL  example.com/b   1.2s",
+		"↷ example.com/c [no test files]",
+		"✖ FAIL example.com/d (0.0s)",
+		"  panic: cut off",
+	}
+	if got := term.Lines(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected screen\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
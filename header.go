@@ -0,0 +1,125 @@
+package scroll
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// The interval the stage header is redrawn at
+const refreshInterval = 100 * time.Millisecond
+
+// The frames of the spinner shown in the stage header
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// A Refresher is a Renderer that redraws the Window periodically, such as to
+// animate the header of the current stage or progress bars. The Buffer calls
+// Refresh from its goroutine on every tick while the header is enabled or a
+// progress bar is pinned, and whenever a progress bar is added or removed.
+type Refresher interface {
+	Refresh(win Window) error
+}
+
+// HeaderText returns the header of the current stage at the provided time: a
+// spinner, the name of the stage and the time elapsed since it started.
+func (win Window) HeaderText(now time.Time) string {
+	elapsed := now.Sub(win.Start)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	frame := spinnerFrames[int(elapsed/refreshInterval)%len(spinnerFrames)]
+
+	parts := []string{frame}
+	if win.Stage != "" {
+		parts = append(parts, win.Stage)
+	}
+	parts = append(parts, formatDuration(elapsed))
+	return strings.Join(parts, " ")
+}
+
+// pinned returns the rows drawn above the scroll window of a Terminal: the
+// stage header if it is enabled followed by the progress bars.
+func (win Window) pinned(now time.Time) []coloredRow {
+	if !win.IsTerm {
+		return nil
+	}
+	var rows []coloredRow
+	if win.Header {
+		rows = append(rows, coloredRow{
+			text:  indentation(win.Depth) + win.HeaderText(now),
+			color: win.getColorWriter(EraserStage),
+		})
+	}
+	for _, p := range win.Progress {
+		rows = append(rows, coloredRow{
+			text:  p.Text(win.width()),
+			color: win.getColorWriter(PrinterStage),
+		})
+	}
+	return rows
+}
+
+// pinnedCount returns the number of rows drawn above the scroll window.
+func (win Window) pinnedCount() int {
+	if !win.IsTerm {
+		return 0
+	}
+	n := len(win.Progress)
+	if win.Header {
+		n++
+	}
+	return n
+}
+
+// A coloredRow is a row drawn with its color.
+type coloredRow struct {
+	text  string
+	color *color.Color
+}
+
+// formatDuration formats a stage duration to a tenth of a second, or to the
+// second once it takes longer than a minute.
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
+
+// animated reports whether the header or progress bars need redrawing on
+// every tick.
+func (b *Buffer) animated() bool {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.header || len(b.bars) > 0
+}
+
+// refresh redraws the header and progress bars on every tick.
+func (b *Buffer) refresh() {
+	if !b.animated() {
+		return
+	}
+	b.redraw()
+}
+
+// SetHeader enables or disables the header line shown above the scroll window
+// of the Buffer. The header shows a spinner, the name of the stage and its
+// elapsed time, and stage messages include the duration of the stage.
+func (b *Buffer) SetHeader(enabled bool) {
+	b.lock.Lock()
+	b.header = enabled
+	b.lock.Unlock()
+
+	// wake the Buffer goroutine to start or stop its ticker
+	select {
+	case b.refresher <- struct{}{}:
+	default:
+	}
+}
+
+// SetHeader enables or disables the header line of the standard Buffer.
+func SetHeader(enabled bool) {
+	std.SetHeader(enabled)
+}
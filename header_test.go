@@ -0,0 +1,96 @@
+package scroll_test
+
+import (
+	"context"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/louislef299/scroll"
+)
+
+func TestStageHeader(t *testing.T) {
+	buff, term := newTermBuffer(t, 40, 10, 2)
+	buff.SetHeader(true)
+
+	build := buff.BeginStage("build")
+	build.Printf("compiling")
+
+	header := regexp.MustCompile(`^. build \d+\.\ds$`)
+	first := term.Screen()[0]
+	if !header.MatchString(first) {
+		t.Fatalf("expected a header, got %q", first)
+	}
+	if got := term.Screen()[1]; got != "compiling" {
+		t.Fatalf("expected the window below the header, got %q", got)
+	}
+
+	// the buffer goroutine keeps the elapsed time ticking
+	deadline := time.Now().Add(2 * time.Second)
+	for term.Screen()[0] == first {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the header to be redrawn, still %q", first)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := term.Screen(); !header.MatchString(got[0]) || got[1] != "compiling" {
+		t.Fatalf("expected the header to be redrawn in place, got %q", got)
+	}
+
+	build.End("")
+	buff.NewStage("linked")
+
+	done := regexp.MustCompile(`^✔ (build|linked) \(\d+\.\ds\)$`)
+	lines := term.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("expected two stage messages and the next header, got %q", lines)
+	}
+	for _, l := range lines[:2] {
+		if !done.MatchString(l) {
+			t.Fatalf("expected a finished stage with its duration, got %q", l)
+		}
+	}
+	if !regexp.MustCompile(`^. \d+\.\ds$`).MatchString(lines[2]) {
+		t.Fatalf("expected the header of the unnamed stage, got %q", lines[2])
+	}
+}
+
+// refreshRenderer counts the times the Buffer refreshes it.
+type refreshRenderer struct {
+	eventRenderer
+	refreshes atomic.Int64
+}
+
+func (r *refreshRenderer) Refresh(win scroll.Window) error {
+	r.refreshes.Add(1)
+	return nil
+}
+
+func TestHeaderTicker(t *testing.T) {
+	r := &refreshRenderer{}
+	buff := scroll.NewWithRenderer(context.Background(), r, 2)
+	defer buff.Close()
+
+	time.Sleep(3 * 100 * time.Millisecond)
+	if n := r.refreshes.Load(); n != 0 {
+		t.Fatalf("expected no refreshes without a header, got %d", n)
+	}
+
+	buff.SetHeader(true)
+	deadline := time.Now().Add(2 * time.Second)
+	for r.refreshes.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the header to be refreshed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	buff.SetHeader(false)
+	time.Sleep(150 * time.Millisecond)
+	n := r.refreshes.Load()
+	time.Sleep(3 * 100 * time.Millisecond)
+	if got := r.refreshes.Load(); got != n {
+		t.Fatalf("expected refreshes to stop with the header, got %d more", got-n)
+	}
+}
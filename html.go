@@ -0,0 +1,78 @@
+package scroll
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/louislef299/scroll/internal/ansihtml"
+)
+
+// ExportHTML writes a single static HTML page of the finished stages of the
+// Buffer to w. Every stage is a collapsible section with its start time,
+// duration and lines, where each line is shown with the time it was written.
+// The printer, stage and stderr colors of the Buffer and any SGR escape
+// sequences in the lines are converted to CSS. Failed stages are expanded.
+// Stages are listed in the order they were begun, each nested stage below its
+// parent.
+//
+// The page must be written before the Buffer is closed, as closing it
+// discards the stage logs.
+func (b *Buffer) ExportHTML(w io.Writer) error {
+	win := Window{Prefix: b.prefix}
+	printer := ansihtml.Style{}.With(int(b.printerColor))
+	stderr := ansihtml.Style{}.With(int(b.stderrColor))
+
+	var sb strings.Builder
+	sb.WriteString(ansihtml.Head)
+	for _, s := range b.stagesByBegin() {
+		c, ok := s.Status.Color()
+		if !ok {
+			c = b.stageColor
+		}
+		title := s.Message
+		if title == "" {
+			title = s.Name
+		}
+		if sym := s.Status.Symbol(); sym != "" {
+			title = sym + " " + title
+		}
+
+		open := ""
+		if s.Status == StatusFail {
+			open = " open"
+		}
+		fmt.Fprintf(&sb, "<details%s style=\"margin-left:%dem\"><summary><span style=\"%s\">%s</span>"+
+			" <span class=\"meta\">%s &middot; %s</span></summary>\n",
+			open, 2*s.Depth, ansihtml.Style{}.With(int(c)).CSS(), html.EscapeString(title),
+			s.Start.Format("15:04:05"), formatDuration(s.Duration()))
+		if s.Err != nil && s.Err.Error() != s.Message {
+			fmt.Fprintf(&sb, "<p class=\"err\">%s</p>\n", html.EscapeString(s.Err.Error()))
+		}
+
+		if s.Log.Len() > 0 {
+			sb.WriteString("<pre>")
+			err := s.Log.Range(func(l Line) bool {
+				base := printer
+				if l.Stderr {
+					base = stderr
+				}
+				fmt.Fprintf(&sb, "<span class=\"ts\">%s</span>%s\n",
+					l.Time.Format("15:04:05.000"), ansihtml.HTML(win.text(l, 0), base))
+				return true
+			})
+			if err != nil {
+				return err
+			}
+			sb.WriteString("</pre>\n")
+		}
+		sb.WriteString("</details>\n")
+	}
+	fmt.Fprintf(&sb, "<p class=\"meta\">exported %s</p>\n</body>\n</html>\n",
+		time.Now().Format(time.RFC3339))
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,47 @@
+package scroll_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/louislef299/scroll"
+)
+
+func TestExportHTML(t *testing.T) {
+	buff := scroll.NewWithRenderer(context.Background(), &eventRenderer{}, 2)
+	defer buff.Close()
+	buff.SetPrinterColor(color.FgYellow)
+	buff.SetStageColor(color.FgCyan)
+
+	build := buff.BeginStage("build")
+	build.Printf("compiling <main>")
+	build.Printf("\033[1;31mred\033[0m plain \033[38;5;21mblue\033[K")
+	build.End("built")
+	buff.Printf("test 0")
+	buff.StageFail(errors.New("tests failed"))
+
+	var sb strings.Builder
+	if err := buff.ExportHTML(&sb); err != nil {
+		t.Fatal(err)
+	}
+	got := sb.String()
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`<details style="margin-left:0em"><summary><span style="color:#00cdcd">built</span>`,
+		`<span style="color:#cdcd00">compiling &lt;main&gt;</span>`,
+		`<span style="color:#cd0000;font-weight:bold">red</span> plain <span style="color:#0000ff">blue</span>`,
+		`<details open style="margin-left:0em"><summary><span style="color:#cd0000">✖ tests failed</span>`,
+		`<span style="color:#cdcd00">test 0</span>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected page to contain\n%s\ngot\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "\033") {
+		t.Errorf("expected no escape sequences in page\n%q", got)
+	}
+}
@@ -0,0 +1,197 @@
+// Package ansihtml converts text with SGR escape sequences to HTML for the
+// HTML exports of scroll.
+package ansihtml
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Head is the start of a page of HTML exported by scroll, which styles
+// converted text like a dark terminal.
+const Head = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>scroll</title>
+<style>
+body { background: #1e1e1e; color: #d4d4d4; font-family: monospace; }
+summary { cursor: pointer; padding: 2px 0; }
+pre { margin: 0 0 0 1.5em; white-space: pre-wrap; }
+.meta, .ts { color: #7f7f7f; }
+.ts { margin-right: 1em; user-select: none; }
+.err { color: #ff0000; margin: 0 0 0 1.5em; }
+</style>
+</head>
+<body>
+`
+
+// palette holds the 16 basic terminal colors.
+var palette = [16]string{
+	"#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+	"#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// Style is the text style set by SGR escape sequences.
+type Style struct {
+	fg, bg                         string
+	bold, faint, italic, underline bool
+}
+
+// With returns the style with the SGR parameters applied.
+func (s Style) With(params ...int) Style {
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == int(color.Reset):
+			s = Style{}
+		case p == int(color.Bold):
+			s.bold = true
+		case p == int(color.Faint):
+			s.faint = true
+		case p == int(color.Italic):
+			s.italic = true
+		case p == int(color.Underline):
+			s.underline = true
+		case p == 22:
+			s.bold, s.faint = false, false
+		case p == 23:
+			s.italic = false
+		case p == 24:
+			s.underline = false
+		case p >= 30 && p <= 37:
+			s.fg = palette[p-30]
+		case p >= 90 && p <= 97:
+			s.fg = palette[p-90+8]
+		case p == 39:
+			s.fg = ""
+		case p >= 40 && p <= 47:
+			s.bg = palette[p-40]
+		case p >= 100 && p <= 107:
+			s.bg = palette[p-100+8]
+		case p == 49:
+			s.bg = ""
+		case p == 38 || p == 48:
+			c, n := extended(params[i+1:])
+			i += n
+			if p == 38 {
+				s.fg = c
+			} else {
+				s.bg = c
+			}
+		}
+	}
+	return s
+}
+
+// extended returns the color of the parameters following 38 or 48, either
+// 5;n for the 256 color palette or 2;r;g;b, and how many were used. Values
+// outside of 0 to 255 give no color.
+func extended(params []int) (string, int) {
+	if len(params) >= 2 && params[0] == 5 {
+		n := params[1]
+		switch {
+		case n < 0:
+		case n < 16:
+			return palette[n], 2
+		case n < 232:
+			levels := [6]int{0, 95, 135, 175, 215, 255}
+			n -= 16
+			return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[n/6%6], levels[n%6]), 2
+		case n < 256:
+			g := 8 + 10*(n-232)
+			return fmt.Sprintf("#%02x%02x%02x", g, g, g), 2
+		}
+		return "", 2
+	}
+	if len(params) >= 4 && params[0] == 2 {
+		for _, v := range params[1:4] {
+			if v < 0 || v > 255 {
+				return "", 4
+			}
+		}
+		return fmt.Sprintf("#%02x%02x%02x", params[1], params[2], params[3]), 4
+	}
+	return "", len(params)
+}
+
+// CSS returns the style as inline CSS.
+func (s Style) CSS() string {
+	var css []string
+	if s.fg != "" {
+		css = append(css, "color:"+s.fg)
+	}
+	if s.bg != "" {
+		css = append(css, "background:"+s.bg)
+	}
+	if s.bold {
+		css = append(css, "font-weight:bold")
+	}
+	if s.faint {
+		css = append(css, "opacity:0.6")
+	}
+	if s.italic {
+		css = append(css, "font-style:italic")
+	}
+	if s.underline {
+		css = append(css, "text-decoration:underline")
+	}
+	return strings.Join(css, ";")
+}
+
+// HTML converts text with SGR escape sequences to escaped HTML, starting with
+// the base style. Other escape sequences are dropped.
+func HTML(text string, base Style) string {
+	var sb strings.Builder
+	style := base
+	var run strings.Builder
+
+	emit := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if css := style.CSS(); css != "" {
+			fmt.Fprintf(&sb, "<span style=\"%s\">%s</span>", css, html.EscapeString(run.String()))
+		} else {
+			sb.WriteString(html.EscapeString(run.String()))
+		}
+		run.Reset()
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\033' {
+			run.WriteByte(text[i])
+			continue
+		}
+		if i+1 >= len(text) || text[i+1] != '[' {
+			// drop two byte escape sequences
+			i++
+			continue
+		}
+
+		// find the final byte of the control sequence
+		j := i + 2
+		for j < len(text) && (text[j] < 0x40 || text[j] > 0x7e) {
+			j++
+		}
+		if j == len(text) {
+			break
+		}
+		if text[j] == 'm' {
+			emit()
+			var params []int
+			for _, f := range strings.Split(text[i+2:j], ";") {
+				p, _ := strconv.Atoi(f)
+				params = append(params, p)
+			}
+			style = style.With(params...)
+		}
+		i = j
+	}
+	emit()
+	return sb.String()
+}
@@ -0,0 +1,26 @@
+package ansihtml
+
+import "testing"
+
+func TestHTMLMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"negative palette", "\033[38;5;-1mtext", "text"},
+		{"palette out of range", "\033[48;5;256mtext", "text"},
+		{"negative rgb", "\033[38;2;-1;0;0mtext", "text"},
+		{"rgb out of range", "\033[38;2;0;300;0mtext", "text"},
+		{"missing parameters", "\033[38;5mtext\033[48m", "text"},
+		{"valid after invalid", "\033[38;5;-1;1mtext", `<span style="font-weight:bold">text</span>`},
+		{"rgb", "\033[38;2;255;0;16mtext", `<span style="color:#ff0010">text</span>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.text, Style{}); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
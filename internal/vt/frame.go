@@ -0,0 +1,92 @@
+package vt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A Frame is a snapshot of the visible screen of a Terminal.
+type Frame struct {
+	Rows    [][]Cell
+	CursorX int
+	CursorY int
+}
+
+// String renders the frame as text. Each non-blank row is printed between
+// pipes and followed by one line per run of non-default attributes, written
+// as [start:end] attr. Trailing blank rows are omitted.
+func (f Frame) String() string {
+	last := -1
+	for i, row := range f.Rows {
+		if rowString(row) != "" {
+			last = i
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "cursor %d,%d\n", f.CursorX, f.CursorY)
+	for _, row := range f.Rows[:last+1] {
+		text := rowString(row)
+		fmt.Fprintf(&sb, "|%s|\n", text)
+		for _, s := range spans(row[:len([]rune(text))]) {
+			fmt.Fprintf(&sb, "  %s\n", s)
+		}
+	}
+	return sb.String()
+}
+
+// spans describes the runs of cells in row that use a non-default attribute.
+func spans(row []Cell) []string {
+	var s []string
+	for start := 0; start < len(row); {
+		end := start + 1
+		for end < len(row) && row[end].Attr == row[start].Attr {
+			end++
+		}
+		if a := row[start].Attr; a != (Attr{}) {
+			s = append(s, fmt.Sprintf("[%d:%d] %s", start, end, a))
+		}
+		start = end
+	}
+	return s
+}
+
+// Snapshot records the current screen as a new Frame.
+func (t *Terminal) Snapshot() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	f := Frame{CursorX: t.x, CursorY: t.y}
+	if f.CursorX >= t.width {
+		f.CursorX = t.width - 1
+	}
+	for _, row := range t.screen {
+		f.Rows = append(f.Rows, append([]Cell(nil), row...))
+	}
+	t.frames = append(t.frames, f)
+}
+
+// Flush records a Frame of the current screen. A scroll Buffer flushes its
+// output after every call, so a frame is captured for each Printf and
+// NewStage.
+func (t *Terminal) Flush() error {
+	t.Snapshot()
+	return nil
+}
+
+// Frames returns every Frame recorded so far.
+func (t *Terminal) Frames() []Frame {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]Frame(nil), t.frames...)
+}
+
+// Golden renders every recorded Frame in order, suitable for AssertGolden.
+func (t *Terminal) Golden() []byte {
+	var buf bytes.Buffer
+	for i, f := range t.Frames() {
+		fmt.Fprintf(&buf, "-- frame %d --\n%s", i+1, f)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,558 @@
+// Package vt provides the headless virtual terminal behind scrolltest and the
+// replay of recordings by the scroll command.
+//
+// A Terminal understands the small subset of VT100/ANSI sequences the scroll
+// package emits (cursor movement, line and display erasure and SGR colors),
+// so the visible screen and the scrollback can be inspected after every
+// write.
+package vt
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Attr describes the SGR attributes of a single Cell. Colors are stored as
+// their SGR parameters, e.g. "31", "95" or "38;5;208", and are empty when the
+// default color is in use.
+type Attr struct {
+	FG        string
+	BG        string
+	Bold      bool
+	Faint     bool
+	Italic    bool
+	Underline bool
+	Reverse   bool
+}
+
+// String returns a compact description of the attribute, or an empty string
+// for the default attribute.
+func (a Attr) String() string {
+	var parts []string
+	if a.FG != "" {
+		parts = append(parts, "fg="+a.FG)
+	}
+	if a.BG != "" {
+		parts = append(parts, "bg="+a.BG)
+	}
+	if a.Bold {
+		parts = append(parts, "bold")
+	}
+	if a.Faint {
+		parts = append(parts, "faint")
+	}
+	if a.Italic {
+		parts = append(parts, "italic")
+	}
+	if a.Underline {
+		parts = append(parts, "underline")
+	}
+	if a.Reverse {
+		parts = append(parts, "reverse")
+	}
+	return strings.Join(parts, " ")
+}
+
+// A Cell is a single character position on the screen.
+type Cell struct {
+	Rune rune
+	Attr Attr
+}
+
+// parser states
+const (
+	stateGround = iota
+	stateEscape
+	stateCSI
+	stateOSC
+	stateOSCEscape
+)
+
+// A Terminal is an in-memory VT100 emulator that implements io.Writer. Line
+// feeds are treated as a carriage return plus line feed, matching a tty with
+// output post-processing enabled, and every rune occupies a single column.
+type Terminal struct {
+	width  int
+	height int
+
+	screen     [][]Cell
+	scrollback [][]Cell
+
+	// cursor position, x == width means a wrap is pending
+	x, y int
+	attr Attr
+
+	savedX, savedY int
+
+	state   int
+	params  []byte
+	partial []byte
+
+	frames []Frame
+
+	lock *sync.Mutex
+}
+
+// New creates a new Terminal with the given screen width and height.
+func New(width, height int) *Terminal {
+	if width < 1 || height < 1 {
+		panic("vt: terminal dimensions must be positive")
+	}
+	t := &Terminal{
+		width:  width,
+		height: height,
+		lock:   &sync.Mutex{},
+	}
+	t.screen = make([][]Cell, height)
+	for i := range t.screen {
+		t.screen[i] = t.blankRow()
+	}
+	return t
+}
+
+// Size returns the width and height of the Terminal.
+func (t *Terminal) Size() (width, height int) {
+	return t.width, t.height
+}
+
+// Write implements io.Writer by interpreting p as terminal output.
+func (t *Terminal) Write(p []byte) (n int, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	buf := p
+	if len(t.partial) > 0 {
+		buf = append(t.partial, p...)
+		t.partial = nil
+	}
+
+	for len(buf) > 0 {
+		r, size := utf8.DecodeRune(buf)
+		if r == utf8.RuneError && size == 1 && !utf8.FullRune(buf) {
+			// keep incomplete multibyte sequences for the next write
+			t.partial = append([]byte(nil), buf...)
+			break
+		}
+		t.handle(r)
+		buf = buf[size:]
+	}
+	return len(p), nil
+}
+
+// handle feeds a single rune through the escape sequence parser.
+func (t *Terminal) handle(r rune) {
+	switch t.state {
+	case stateEscape:
+		t.escape(r)
+		return
+	case stateCSI:
+		if r >= 0x40 && r <= 0x7e {
+			t.csi(r)
+			t.state = stateGround
+		} else {
+			t.params = append(t.params, byte(r))
+		}
+		return
+	case stateOSC:
+		switch r {
+		case '\a':
+			t.state = stateGround
+		case 0x1b:
+			t.state = stateOSCEscape
+		}
+		return
+	case stateOSCEscape:
+		// ST is ESC \, anything else keeps the string going
+		if r == '\\' {
+			t.state = stateGround
+		} else {
+			t.state = stateOSC
+		}
+		return
+	}
+
+	switch r {
+	case 0x1b:
+		t.state = stateEscape
+	case '\n', '\v', '\f':
+		t.carriageReturn()
+		t.lineFeed()
+	case '\r':
+		t.carriageReturn()
+	case '\b':
+		t.clampX()
+		if t.x > 0 {
+			t.x--
+		}
+	case '\t':
+		t.clampX()
+		t.x = (t.x/8 + 1) * 8
+		if t.x >= t.width {
+			t.x = t.width - 1
+		}
+	default:
+		if r < 0x20 || r == 0x7f {
+			return // ignore remaining control characters
+		}
+		t.put(r)
+	}
+}
+
+// escape handles the byte following an ESC.
+func (t *Terminal) escape(r rune) {
+	t.state = stateGround
+	switch r {
+	case '[':
+		t.state = stateCSI
+		t.params = t.params[:0]
+	case ']':
+		t.state = stateOSC
+	case '7':
+		t.savedX, t.savedY = t.x, t.y
+	case '8':
+		t.x, t.y = t.savedX, t.savedY
+	case 'D':
+		t.lineFeed()
+	case 'E':
+		t.carriageReturn()
+		t.lineFeed()
+	case 'M':
+		t.reverseIndex()
+	case 'c':
+		t.reset()
+	}
+}
+
+// csi executes a complete control sequence with the given final byte.
+func (t *Terminal) csi(final rune) {
+	if len(t.params) > 0 && (t.params[0] == '?' || t.params[0] == '>') {
+		return // private modes such as cursor visibility are ignored
+	}
+	args := parseParams(string(t.params))
+	n := arg(args, 0, 1)
+
+	switch final {
+	case 'A':
+		t.clampX()
+		t.y = clamp(t.y-n, 0, t.height-1)
+	case 'B':
+		t.clampX()
+		t.y = clamp(t.y+n, 0, t.height-1)
+	case 'C':
+		t.clampX()
+		t.x = clamp(t.x+n, 0, t.width-1)
+	case 'D':
+		t.clampX()
+		t.x = clamp(t.x-n, 0, t.width-1)
+	case 'E':
+		t.x = 0
+		t.y = clamp(t.y+n, 0, t.height-1)
+	case 'F':
+		t.x = 0
+		t.y = clamp(t.y-n, 0, t.height-1)
+	case 'G':
+		t.x = clamp(n-1, 0, t.width-1)
+	case 'H', 'f':
+		t.y = clamp(arg(args, 0, 1)-1, 0, t.height-1)
+		t.x = clamp(arg(args, 1, 1)-1, 0, t.width-1)
+	case 'J':
+		t.eraseDisplay(arg(args, 0, 0))
+	case 'K':
+		t.eraseLine(arg(args, 0, 0))
+	case 'S':
+		for i := 0; i < n; i++ {
+			t.scrollUp()
+		}
+	case 'm':
+		t.sgr(args)
+	}
+}
+
+// sgr applies Select Graphic Rendition parameters to the current attribute.
+func (t *Terminal) sgr(args []int) {
+	if len(args) == 0 {
+		t.attr = Attr{}
+		return
+	}
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == 0:
+			t.attr = Attr{}
+		case a == 1:
+			t.attr.Bold = true
+		case a == 2:
+			t.attr.Faint = true
+		case a == 3:
+			t.attr.Italic = true
+		case a == 4:
+			t.attr.Underline = true
+		case a == 7:
+			t.attr.Reverse = true
+		case a == 22:
+			t.attr.Bold, t.attr.Faint = false, false
+		case a == 23:
+			t.attr.Italic = false
+		case a == 24:
+			t.attr.Underline = false
+		case a == 27:
+			t.attr.Reverse = false
+		case (a >= 30 && a <= 37) || (a >= 90 && a <= 97):
+			t.attr.FG = strconv.Itoa(a)
+		case a == 39:
+			t.attr.FG = ""
+		case (a >= 40 && a <= 47) || (a >= 100 && a <= 107):
+			t.attr.BG = strconv.Itoa(a)
+		case a == 49:
+			t.attr.BG = ""
+		case a == 38 || a == 48:
+			color, skip := extendedColor(args[i:])
+			if a == 38 {
+				t.attr.FG = color
+			} else {
+				t.attr.BG = color
+			}
+			i += skip
+		}
+	}
+}
+
+// extendedColor parses a 256 color or true color SGR parameter list starting
+// at 38 or 48. It returns the color and the number of extra parameters used.
+func extendedColor(args []int) (string, int) {
+	if len(args) >= 3 && args[1] == 5 {
+		return strconv.Itoa(args[0]) + ";5;" + strconv.Itoa(args[2]), 2
+	}
+	if len(args) >= 5 && args[1] == 2 {
+		return strconv.Itoa(args[0]) + ";2;" + strconv.Itoa(args[2]) + ";" +
+			strconv.Itoa(args[3]) + ";" + strconv.Itoa(args[4]), 4
+	}
+	return "", len(args) - 1
+}
+
+// put writes a printable rune at the cursor and advances it.
+func (t *Terminal) put(r rune) {
+	if t.x >= t.width {
+		t.carriageReturn()
+		t.lineFeed()
+	}
+	t.screen[t.y][t.x] = Cell{Rune: r, Attr: t.attr}
+	t.x++
+}
+
+func (t *Terminal) carriageReturn() {
+	t.x = 0
+}
+
+// lineFeed moves the cursor down a line, scrolling the screen when the cursor
+// is already on the bottom row.
+func (t *Terminal) lineFeed() {
+	t.clampX()
+	if t.y == t.height-1 {
+		t.scrollUp()
+		return
+	}
+	t.y++
+}
+
+// reverseIndex moves the cursor up a line, scrolling the screen down when the
+// cursor is already on the top row.
+func (t *Terminal) reverseIndex() {
+	t.clampX()
+	if t.y > 0 {
+		t.y--
+		return
+	}
+	copy(t.screen[1:], t.screen[:t.height-1])
+	t.screen[0] = t.blankRow()
+}
+
+// scrollUp moves the top row of the screen into the scrollback.
+func (t *Terminal) scrollUp() {
+	t.scrollback = append(t.scrollback, t.screen[0])
+	copy(t.screen, t.screen[1:])
+	t.screen[t.height-1] = t.blankRow()
+}
+
+// eraseLine implements EL: 0 erases to the end of the line, 1 to the start of
+// the line and 2 the entire line.
+func (t *Terminal) eraseLine(mode int) {
+	t.clampX()
+	row := t.screen[t.y]
+	switch mode {
+	case 0:
+		clearCells(row[t.x:])
+	case 1:
+		clearCells(row[:t.x+1])
+	case 2:
+		clearCells(row)
+	}
+}
+
+// eraseDisplay implements ED: 0 erases below the cursor, 1 above the cursor, 2
+// the entire screen and 3 the entire screen and scrollback.
+func (t *Terminal) eraseDisplay(mode int) {
+	t.clampX()
+	switch mode {
+	case 0:
+		clearCells(t.screen[t.y][t.x:])
+		for _, row := range t.screen[t.y+1:] {
+			clearCells(row)
+		}
+	case 1:
+		clearCells(t.screen[t.y][:t.x+1])
+		for _, row := range t.screen[:t.y] {
+			clearCells(row)
+		}
+	case 2, 3:
+		for _, row := range t.screen {
+			clearCells(row)
+		}
+		if mode == 3 {
+			t.scrollback = nil
+		}
+	}
+}
+
+func (t *Terminal) reset() {
+	for _, row := range t.screen {
+		clearCells(row)
+	}
+	t.scrollback = nil
+	t.x, t.y = 0, 0
+	t.attr = Attr{}
+}
+
+// clampX resolves a pending wrap before a cursor movement.
+func (t *Terminal) clampX() {
+	if t.x >= t.width {
+		t.x = t.width - 1
+	}
+}
+
+func (t *Terminal) blankRow() []Cell {
+	row := make([]Cell, t.width)
+	clearCells(row)
+	return row
+}
+
+// Screen returns the visible rows of the Terminal with trailing spaces
+// removed.
+func (t *Terminal) Screen() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return rowStrings(t.screen)
+}
+
+// Scrollback returns the rows that have scrolled off the top of the screen,
+// oldest first.
+func (t *Terminal) Scrollback() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return rowStrings(t.scrollback)
+}
+
+// Lines returns the scrollback followed by the visible rows, with trailing
+// blank rows removed. This is everything the user could scroll through.
+func (t *Terminal) Lines() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	lines := append(rowStrings(t.scrollback), rowStrings(t.screen)...)
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Cells returns the cells of the scrollback followed by the visible rows,
+// with trailing blank rows removed, like Lines.
+func (t *Terminal) Cells() [][]Cell {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var rows [][]Cell
+	for _, row := range append(t.scrollback[:len(t.scrollback):len(t.scrollback)], t.screen...) {
+		rows = append(rows, append([]Cell(nil), row...))
+	}
+	for len(rows) > 0 && rowString(rows[len(rows)-1]) == "" {
+		rows = rows[:len(rows)-1]
+	}
+	return rows
+}
+
+// Row returns a copy of the cells of the visible row y.
+func (t *Terminal) Row(y int) []Cell {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]Cell(nil), t.screen[y]...)
+}
+
+// Cursor returns the current cursor position.
+func (t *Terminal) Cursor() (x, y int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.x >= t.width {
+		return t.width - 1, t.y
+	}
+	return t.x, t.y
+}
+
+// String returns the visible screen as newline separated rows.
+func (t *Terminal) String() string {
+	return strings.Join(t.Screen(), "\n")
+}
+
+func rowStrings(rows [][]Cell) []string {
+	s := make([]string, len(rows))
+	for i, row := range rows {
+		s[i] = rowString(row)
+	}
+	return s
+}
+
+func rowString(row []Cell) string {
+	var sb strings.Builder
+	for _, c := range row {
+		sb.WriteRune(c.Rune)
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
+
+func clearCells(cells []Cell) {
+	for i := range cells {
+		cells[i] = Cell{Rune: ' '}
+	}
+}
+
+// parseParams splits CSI parameters, treating empty parameters as zero.
+func parseParams(s string) []int {
+	if s == "" {
+		return nil
+	}
+	fields := strings.Split(s, ";")
+	args := make([]int, len(fields))
+	for i, f := range fields {
+		args[i], _ = strconv.Atoi(f)
+	}
+	return args
+}
+
+// arg returns the i'th parameter, or def when it is missing or zero.
+func arg(args []int, i, def int) int {
+	if i >= len(args) || args[i] == 0 {
+		return def
+	}
+	return args[i]
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package scroll
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONRenderer is a Renderer that writes one JSON object per event instead of
+// drawing a scroll window, for output that is read by other tools. Every
+// object has a type of "line", "stage_begin" or "stage_end":
+//
+//	{"type":"stage_begin","stage":"build","ts":"..."}
+//	{"type":"line","stage":"build","ts":"...","msg":"compiling"}
+//	{"type":"stage_end","stage":"build","ts":"...","msg":"built","status":"ok","duration_ms":1200,"lines":1}
+//
+// Lines are written without the prefix of the Buffer and the object of a
+// failed stage includes its error.
+type JSONRenderer struct {
+	w io.Writer
+}
+
+// jsonEvent is the encoding of an event written by the JSONRenderer.
+type jsonEvent struct {
+	Type       string    `json:"type"`
+	Stage      string    `json:"stage,omitempty"`
+	Depth      int       `json:"depth,omitempty"`
+	Time       time.Time `json:"ts"`
+	Msg        string    `json:"msg,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS *int64    `json:"duration_ms,omitempty"`
+	Lines      *int      `json:"lines,omitempty"`
+}
+
+// NewJSONRenderer creates a new JSONRenderer writing to w.
+func NewJSONRenderer(w io.Writer) *JSONRenderer {
+	return &JSONRenderer{w: w}
+}
+
+// SetOutput sets the destination output for the JSONRenderer.
+func (r *JSONRenderer) SetOutput(w io.Writer) {
+	r.w = w
+}
+
+// Line writes a "line" event.
+func (r *JSONRenderer) Line(win Window, l Line) error {
+	return r.write(jsonEvent{
+		Type:  "line",
+		Stage: win.Stage,
+		Depth: win.Depth,
+		Time:  l.Time,
+		Msg:   l.Text,
+	})
+}
+
+// StageBegin writes a "stage_begin" event.
+func (r *JSONRenderer) StageBegin(win Window) error {
+	return r.write(jsonEvent{
+		Type:  "stage_begin",
+		Stage: win.Stage,
+		Depth: win.Depth,
+		Time:  win.Start,
+	})
+}
+
+// StageEnd writes a "stage_end" event with the outcome of the stage.
+func (r *JSONRenderer) StageEnd(win Window, s StageResult) error {
+	duration := s.Duration().Milliseconds()
+	lines := s.Log.Len()
+	e := jsonEvent{
+		Type:       "stage_end",
+		Stage:      s.Name,
+		Depth:      s.Depth,
+		Time:       s.End,
+		Msg:        s.Message,
+		Status:     s.Status.String(),
+		DurationMS: &duration,
+		Lines:      &lines,
+	}
+	if s.Err != nil {
+		e.Error = s.Err.Error()
+	}
+	return r.write(e)
+}
+
+// Erase implements Renderer. Written events can't be erased.
+func (r *JSONRenderer) Erase(win Window) error {
+	return nil
+}
+
+// Close implements Renderer.
+func (r *JSONRenderer) Close() error {
+	return nil
+}
+
+// write encodes the event as a single line of JSON.
+func (r *JSONRenderer) write(e jsonEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := r.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return flush(r.w)
+}
@@ -0,0 +1,66 @@
+package scroll_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/louislef299/scroll"
+)
+
+func TestJSONRenderer(t *testing.T) {
+	var out bytes.Buffer
+	buff := scroll.NewWithRenderer(context.Background(), scroll.NewJSONRenderer(&out), 2)
+	buff.SetPrefix("=>")
+
+	build := buff.BeginStage("build")
+	build.Printf("compiling")
+	build.OK("built")
+	buff.Printf("testing")
+	buff.StageFail(errors.New("tests failed"))
+	if err := buff.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	type event struct {
+		Type       string `json:"type"`
+		Stage      string `json:"stage"`
+		Msg        string `json:"msg"`
+		Status     string `json:"status"`
+		Error      string `json:"error"`
+		DurationMS *int64 `json:"duration_ms"`
+		Lines      *int   `json:"lines"`
+		TS         string `json:"ts"`
+	}
+	var got []event
+	s := bufio.NewScanner(&out)
+	for s.Scan() {
+		var e event
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatalf("invalid event %q: %v", s.Text(), err)
+		}
+		if e.TS == "" {
+			t.Errorf("event %q has no timestamp", s.Text())
+		}
+		if e.Type == "stage_end" && (e.DurationMS == nil || e.Lines == nil) {
+			t.Errorf("event %q has no duration or lines", s.Text())
+		}
+		e.TS, e.DurationMS, e.Lines = "", nil, nil
+		got = append(got, e)
+	}
+
+	want := []event{
+		{Type: "stage_begin", Stage: "build"},
+		{Type: "line", Stage: "build", Msg: "compiling"},
+		{Type: "stage_end", Stage: "build", Msg: "built", Status: "ok"},
+		{Type: "line", Msg: "testing"},
+		{Type: "stage_end", Msg: "tests failed", Status: "fail", Error: "tests failed"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected events\n%+v\ngot\n%+v", want, got)
+	}
+}
@@ -0,0 +1,117 @@
+package scroll
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// junitSuites is the root element of a JUnit XML report.
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ExportJUnit writes a JUnit XML report of the finished stages of the Buffer
+// to w. The report has a single test suite with the given name, where every
+// stage is a test case with its duration and the lines written during the
+// stage as system-out. Failed stages are reported as failures with the text
+// of their error, and skipped stages as skipped. Test cases are in the order
+// their stages were begun.
+//
+// The report must be written before the Buffer is closed, as closing it
+// discards the stage logs.
+func (b *Buffer) ExportJUnit(w io.Writer, suite string) error {
+	stages := b.stagesByBegin()
+	s := junitSuite{Name: suite}
+
+	var start, end time.Time
+	for _, st := range stages {
+		c := junitCase{
+			Name:      stageName(st),
+			Classname: suite,
+			Time:      junitTime(st.Duration()),
+		}
+		if start.IsZero() || st.Start.Before(start) {
+			start = st.Start
+		}
+		if st.End.After(end) {
+			end = st.End
+		}
+
+		var out strings.Builder
+		if _, err := st.Log.WriteTo(&out); err != nil {
+			return err
+		}
+		c.SystemOut = out.String()
+
+		switch st.Status {
+		case StatusFail:
+			s.Failures++
+			c.Failure = &junitMessage{Message: st.Message}
+			if st.Err != nil {
+				c.Failure.Text = st.Err.Error()
+			}
+		case StatusSkip:
+			s.Skipped++
+			c.Skipped = &junitMessage{Message: st.Message}
+		}
+		s.Cases = append(s.Cases, c)
+	}
+	s.Tests = len(s.Cases)
+	s.Time = junitTime(end.Sub(start))
+	if !start.IsZero() {
+		s.Timestamp = start.Format("2006-01-02T15:04:05")
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{s}}); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// stageName returns the name of the stage, falling back to its message for
+// stages finished with NewStage.
+func stageName(s StageResult) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Message
+}
+
+// junitTime formats a duration as seconds.
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
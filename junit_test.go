@@ -0,0 +1,69 @@
+package scroll_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/louislef299/scroll"
+)
+
+func TestExportJUnit(t *testing.T) {
+	buff := scroll.NewWithRenderer(context.Background(), &eventRenderer{}, 2)
+	defer buff.Close()
+
+	build := buff.BeginStage("build")
+	build.Printf("compiling")
+	build.OK("built")
+	buff.StageSkip("lint skipped")
+	buff.Printf("test 0")
+	buff.Printf("test 1")
+	buff.StageFail(errors.New("tests failed"))
+
+	var out bytes.Buffer
+	if err := buff.ExportJUnit(&out, "ci"); err != nil {
+		t.Fatal(err)
+	}
+
+	var report struct {
+		Suites []struct {
+			Name     string `xml:"name,attr"`
+			Tests    int    `xml:"tests,attr"`
+			Failures int    `xml:"failures,attr"`
+			Skipped  int    `xml:"skipped,attr"`
+			Cases    []struct {
+				Name    string `xml:"name,attr"`
+				Time    string `xml:"time,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+					Text    string `xml:",chardata"`
+				} `xml:"failure"`
+				Skipped   *struct{} `xml:"skipped"`
+				SystemOut string    `xml:"system-out"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid report %s: %v", out.String(), err)
+	}
+	if len(report.Suites) != 1 {
+		t.Fatalf("expected one test suite, got %d", len(report.Suites))
+	}
+	s := report.Suites[0]
+	if s.Name != "ci" || s.Tests != 3 || s.Failures != 1 || s.Skipped != 1 {
+		t.Fatalf("unexpected test suite %+v", s)
+	}
+
+	build0, lint, test := s.Cases[0], s.Cases[1], s.Cases[2]
+	if build0.Name != "build" || build0.SystemOut != "compiling\n" || build0.Failure != nil || build0.Time == "" {
+		t.Errorf("unexpected build test case %+v", build0)
+	}
+	if lint.Name != "lint skipped" || lint.Skipped == nil {
+		t.Errorf("unexpected lint test case %+v", lint)
+	}
+	if test.SystemOut != "test 0\ntest 1\n" || test.Failure == nil || test.Failure.Text != "tests failed" {
+		t.Errorf("unexpected test test case %+v", test)
+	}
+}
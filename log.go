@@ -0,0 +1,254 @@
+package scroll
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Represents the default number of bytes a stage Log keeps in memory
+const DEFAULT_HISTORY_BUDGET = 1 << 20
+
+// A Log stores every line written during a stage. Lines are kept in memory
+// until the byte budget of the Log is used up, after which they are spilled
+// to a temporary file. A Log with a line limit only keeps its last lines in
+// memory instead. A Log is safe for concurrent use.
+type Log struct {
+	// The max number of text bytes kept in memory, and the max number of
+	// lines kept at all if limited
+	budget int
+	limit  int
+
+	// The lines kept in memory and the bytes of text they hold
+	lines []Line
+	size  int
+
+	// The temporary file lines are spilled to and its length in bytes. The
+	// file is only held open while lines are appended to it.
+	path    string
+	file    *os.File
+	spill   *bufio.Writer
+	spilled int64
+
+	count  int
+	closed bool
+
+	lock *sync.Mutex
+}
+
+// spillLine is the encoding of a Line in the spill file.
+type spillLine struct {
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time"`
+	Stderr bool      `json:"stderr,omitempty"`
+}
+
+// newLog creates an empty Log that keeps up to budget bytes in memory, or
+// only its last limit lines if limit is positive.
+func newLog(budget, limit int) *Log {
+	return &Log{
+		budget: budget,
+		limit:  limit,
+		lock:   &sync.Mutex{},
+	}
+}
+
+// setLimits sets the budget and line limit of the Log if nothing was
+// appended to it yet.
+func (l *Log) setLimits(budget, limit int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.count == 0 && l.path == "" {
+		l.budget, l.limit = budget, limit
+	}
+}
+
+// append adds a line to the Log, spilling it to disk when the budget is used
+// up.
+func (l *Log) append(line Line) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return fmt.Errorf("scroll: append to closed stage log")
+	}
+	if l.limit > 0 {
+		l.lines = append(l.lines, line)
+		if len(l.lines) >= 2*l.limit {
+			// drop older lines in batches, into a new array as Range may
+			// still be reading the old one
+			l.lines = append(make([]Line, 0, 2*l.limit), l.lines[len(l.lines)-l.limit:]...)
+		}
+		l.count = len(l.lines)
+		if l.count > l.limit {
+			l.count = l.limit
+		}
+		return nil
+	}
+
+	l.count++
+	if l.path == "" && l.size+len(line.Text) <= l.budget {
+		l.lines = append(l.lines, line)
+		l.size += len(line.Text)
+		return nil
+	}
+
+	if l.file == nil {
+		var f *os.File
+		var err error
+		if l.path == "" {
+			f, err = os.CreateTemp("", "scroll-stage-*.log")
+		} else {
+			f, err = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0)
+		}
+		if err != nil {
+			return err
+		}
+		l.path = f.Name()
+		l.file = f
+		l.spill = bufio.NewWriter(f)
+	}
+	b, err := json.Marshal(spillLine{Text: line.Text, Time: line.Time, Stderr: line.Stderr})
+	if err != nil {
+		return err
+	}
+	n, err := l.spill.Write(append(b, '\n'))
+	l.spilled += int64(n)
+	return err
+}
+
+// Len returns the number of lines in the Log, which is at most its limit.
+func (l *Log) Len() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.count
+}
+
+// Spilled reports whether lines of the Log have been spilled to disk.
+func (l *Log) Spilled() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.path != ""
+}
+
+// Range calls fn for every line of the Log, oldest first, until fn returns
+// false. Lines appended while iterating may not be included.
+func (l *Log) Range(fn func(Line) bool) error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return fmt.Errorf("scroll: read from closed stage log")
+	}
+	lines := l.lines[:len(l.lines):len(l.lines)]
+	if l.limit > 0 && len(lines) > l.limit {
+		lines = lines[len(lines)-l.limit:]
+	}
+	spilled := l.spilled
+	if l.file != nil {
+		if err := l.spill.Flush(); err != nil {
+			l.lock.Unlock()
+			return err
+		}
+	}
+	name := l.path
+	l.lock.Unlock()
+
+	for _, line := range lines {
+		if !fn(line) {
+			return nil
+		}
+	}
+	if name == "" {
+		return nil
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// only read what was spilled so far, the file may still be growing
+	s := bufio.NewScanner(io.LimitReader(f, spilled))
+	s.Buffer(nil, 1<<30)
+	for s.Scan() {
+		var sl spillLine
+		if err := json.Unmarshal(s.Bytes(), &sl); err != nil {
+			return err
+		}
+		if !fn(Line{Text: sl.Text, Time: sl.Time, Stderr: sl.Stderr}) {
+			return nil
+		}
+	}
+	return s.Err()
+}
+
+// Lines returns every line of the Log, oldest first.
+func (l *Log) Lines() ([]Line, error) {
+	var lines []Line
+	err := l.Range(func(line Line) bool {
+		lines = append(lines, line)
+		return true
+	})
+	return lines, err
+}
+
+// WriteTo dumps the text of every line of the Log to w, one per line.
+func (l *Log) WriteTo(w io.Writer) (n int64, err error) {
+	rangeErr := l.Range(func(line Line) bool {
+		var m int
+		m, err = fmt.Fprintln(w, line.Text)
+		n += int64(m)
+		return err == nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, rangeErr
+}
+
+// release flushes the lines spilled to disk and closes the spill file, which
+// is reopened when the Log is read. It is called once the stage is finished
+// so finished stages don't hold on to file descriptors.
+func (l *Log) release() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.closeFile()
+}
+
+// closeFile flushes and closes the spill file if it is open.
+func (l *Log) closeFile() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.spill.Flush()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file, l.spill = nil, nil
+	return err
+}
+
+// Close removes the spill file of the Log. The Log can't be used afterwards.
+func (l *Log) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	l.lines = nil
+	if l.path == "" {
+		return nil
+	}
+	err := l.closeFile()
+	if rmErr := os.Remove(l.path); err == nil {
+		err = rmErr
+	}
+	return err
+}
@@ -0,0 +1,209 @@
+package scroll_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/louislef299/scroll"
+)
+
+func TestStageLogSpill(t *testing.T) {
+	buff := scroll.NewWithRenderer(context.Background(), &eventRenderer{}, 3)
+	// the first stage already has a line, so it keeps the default budget
+	buff.Printf("before budget")
+	buff.SetHistoryBudget(64)
+	buff.StageOK("first stage")
+
+	var want bytes.Buffer
+	for i := 0; i < 50; i++ {
+		buff.Printf("line %d of the stage", i)
+		fmt.Fprintf(&want, "line %d of the stage\n", i)
+	}
+
+	log := buff.StageLog()
+	if !log.Spilled() {
+		t.Fatal("expected the stage log to spill to disk")
+	}
+	if log.Len() != 50 {
+		t.Fatalf("expected 50 lines, got %d", log.Len())
+	}
+
+	var got bytes.Buffer
+	if _, err := log.WriteTo(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		t.Fatalf("expected log\n%s\ngot\n%s", want.String(), got.String())
+	}
+
+	buff.StageOK("second stage")
+	stages := buff.Stages()
+	if len(stages) != 2 {
+		t.Fatalf("expected 2 stages, got %d", len(stages))
+	}
+	if stages[0].Log.Spilled() || stages[0].Log.Len() != 1 {
+		t.Fatalf("expected the first stage to stay in memory with 1 line")
+	}
+	if stages[1].Log != log {
+		t.Fatal("expected the finished stage to keep its log")
+	}
+
+	if err := buff.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Range(func(scroll.Line) bool { return true }); err == nil {
+		t.Fatal("expected reading a closed log to fail")
+	}
+}
+
+func TestStageLogRange(t *testing.T) {
+	buff := scroll.NewWithRenderer(context.Background(), &eventRenderer{}, 3)
+	defer buff.Close()
+	buff.SetHistoryBudget(0)
+	buff.StageOK("spill everything from now on")
+
+	for i := 0; i < 5; i++ {
+		buff.Printf("line %d", i)
+	}
+
+	var seen []string
+	err := buff.StageLog().Range(func(l scroll.Line) bool {
+		seen = append(seen, l.Text)
+		return len(seen) < 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 2 || seen[1] != "line 1" {
+		t.Fatalf("expected iteration to stop after 2 lines, got %q", seen)
+	}
+}
+
+// openFiles returns the files in dir the process holds open.
+func openFiles(t *testing.T, dir string) []string {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("open files can't be listed:", err)
+	}
+	var open []string
+	for _, fd := range fds {
+		path, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err == nil && strings.HasPrefix(path, dir) {
+			open = append(open, path)
+		}
+	}
+	return open
+}
+
+func TestStageLogRelease(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	buff := scroll.NewWithRenderer(ctx, &eventRenderer{}, 3)
+	buff.SetHistoryBudget(0)
+	buff.StageOK("spill everything from now on")
+
+	for i := 0; i < 3; i++ {
+		buff.Printf("line %d", i)
+	}
+	buff.StageOK("spilled")
+	if open := openFiles(t, dir); len(open) != 0 {
+		t.Fatalf("expected the finished stage to release its spill file, got %q", open)
+	}
+
+	stages := buff.Stages()
+	lines, err := stages[len(stages)-1].Log.Lines()
+	if err != nil || len(lines) != 3 {
+		t.Fatalf("expected the spilled lines to be read back, got %v, %v", lines, err)
+	}
+
+	// cancelling the Buffer removes the spill files without Close
+	buff.Printf("open stage")
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the spill files to be removed, got %v", entries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStageLogLimit(t *testing.T) {
+	buff := scroll.NewWithRenderer(context.Background(), &eventRenderer{}, 3)
+	defer buff.Close()
+	buff.SetHistoryBudget(0)
+	// the limit applies to the unnamed stage as nothing was printed to it
+	buff.SetHistoryLimit(3)
+
+	for i := 0; i < 10; i++ {
+		buff.Printf("line %d", i)
+	}
+	log := buff.StageLog()
+	if log.Spilled() || log.Len() != 3 {
+		t.Fatalf("expected 3 lines kept in memory, got %d, spilled %t", log.Len(), log.Spilled())
+	}
+
+	buff.SetHistoryLimit(5)
+	if log.Len() != 3 {
+		t.Fatalf("expected the limit of a stage with lines to be kept, got %d lines", log.Len())
+	}
+	buff.StageOK("limit lines from now on")
+	for i := 0; i < 10; i++ {
+		buff.Printf("line %d", i)
+	}
+	if got := buff.StageLog().Len(); got != 5 {
+		t.Fatalf("expected the next stage to keep 5 lines, got %d", got)
+	}
+	buff.StageOK("limited")
+
+	var got bytes.Buffer
+	if _, err := log.WriteTo(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := "line 7\nline 8\nline 9\n"; got.String() != want {
+		t.Fatalf("expected the last lines\n%s\ngot\n%s", want, got.String())
+	}
+}
+
+func TestStageLimit(t *testing.T) {
+	buff := scroll.NewWithRenderer(context.Background(), &eventRenderer{}, 3)
+	defer buff.Close()
+	buff.SetHistoryBudget(0)
+	buff.SetStageLimit(2)
+
+	var logs []*scroll.Log
+	for i := 0; i < 4; i++ {
+		buff.Printf("line %d", i)
+		logs = append(logs, buff.StageLog())
+		buff.StageOK("stage %d", i)
+	}
+
+	stages := buff.Stages()
+	if len(stages) != 2 || stages[0].Message != "stage 2" || stages[1].Message != "stage 3" {
+		t.Fatalf("expected the last 2 stages, got %+v", stages)
+	}
+	if !logs[0].Spilled() {
+		t.Fatal("expected the stage log to spill to disk")
+	}
+	if err := logs[0].Range(func(scroll.Line) bool { return true }); err == nil {
+		t.Fatal("expected the log of a dropped stage to be closed")
+	}
+	if err := logs[3].Range(func(scroll.Line) bool { return true }); err != nil {
+		t.Fatalf("expected the log of a kept stage to be readable, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package scroll
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// A Manager owns a terminal and hosts several Buffers stacked vertically at
+// the bottom of it, in the order they were created. Each Buffer keeps its own
+// window size, header and stages, while the Manager redraws all of them
+// whenever one changes. Stage messages are printed above the stacked
+// windows. A Manager is safe for concurrent use.
+type Manager struct {
+	w     io.Writer
+	plain *PlainRenderer
+
+	// The windows of the hosted Buffers, in the order they are drawn
+	regions []*region
+
+	// Represents the number of rows currently drawn for all regions
+	rows int
+
+	lock *sync.Mutex
+}
+
+// region is the Renderer of a Buffer hosted by a Manager. It keeps the latest
+// Window of the Buffer for the Manager to draw.
+type region struct {
+	m   *Manager
+	win Window
+}
+
+// NewManager creates a new Manager writing to w.
+func NewManager(w io.Writer) *Manager {
+	return &Manager{
+		w:     w,
+		plain: NewPlainRenderer(w),
+		lock:  &sync.Mutex{},
+	}
+}
+
+// New creates a new Buffer drawn below the Buffers already hosted by the
+// Manager. The Buffer is removed from the Manager once it is closed or its
+// context is done.
+func (m *Manager) New(ctx context.Context, bufferSize int) *Buffer {
+	r := &region{m: m}
+
+	m.lock.Lock()
+	m.regions = append(m.regions, r)
+	m.lock.Unlock()
+
+	b := NewWithRenderer(ctx, r, bufferSize)
+
+	m.lock.Lock()
+	r.win = b.window()
+	m.lock.Unlock()
+	return b
+}
+
+// Line implements Renderer. Lines of a Buffer that is not a Terminal are
+// printed above the stacked windows by a PlainRenderer.
+func (r *region) Line(win Window, l Line) error {
+	if !win.IsTerm {
+		return r.m.update(r, win, func(io.Writer) error {
+			return r.m.plain.Line(win, l)
+		})
+	}
+	return r.m.update(r, win, nil)
+}
+
+// StageBegin implements Renderer.
+func (r *region) StageBegin(win Window) error {
+	return r.m.update(r, win, nil)
+}
+
+// StageEnd implements Renderer by printing the stage message above the
+// stacked windows.
+func (r *region) StageEnd(win Window, s StageResult) error {
+	return r.m.update(r, win, func(w io.Writer) error {
+		if !win.IsTerm {
+			return r.m.plain.StageEnd(win, s)
+		}
+		return printStageEnd(w, win, s)
+	})
+}
+
+// Erase implements Renderer.
+func (r *region) Erase(win Window) error {
+	return r.m.update(r, win, nil)
+}
+
+// Refresh implements Refresher.
+func (r *region) Refresh(win Window) error {
+	return r.m.update(r, win, nil)
+}
+
+// Close implements Renderer by removing the window from the Manager.
+func (r *region) Close() error {
+	m := r.m
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, reg := range m.regions {
+		if reg == r {
+			m.regions = append(m.regions[:i:i], m.regions[i+1:]...)
+			break
+		}
+	}
+	if !r.win.IsTerm {
+		if err := m.plain.Close(); err != nil {
+			return err
+		}
+	}
+	if err := m.erase(); err != nil {
+		return err
+	}
+	if err := m.draw(); err != nil {
+		return err
+	}
+	return flush(m.w)
+}
+
+// update stores the latest Window of a region and redraws every region. The
+// output of above, if any, is printed above the regions first.
+func (m *Manager) update(r *region, win Window, above func(io.Writer) error) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	r.win = win
+	if err := m.erase(); err != nil {
+		return err
+	}
+	if above != nil {
+		if err := above(m.w); err != nil {
+			return err
+		}
+	}
+	if err := m.draw(); err != nil {
+		return err
+	}
+	return flush(m.w)
+}
+
+// draw prints the windows of every region below the cursor.
+func (m *Manager) draw() error {
+	for _, r := range m.regions {
+		if !r.win.IsTerm {
+			continue
+		}
+		rows, err := drawWindow(m.w, r.win)
+		m.rows += rows
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// erase erases the rows of every region.
+func (m *Manager) erase() error {
+	lines := m.rows
+	m.rows = 0
+	return eraseLines(m.w, lines)
+}
@@ -0,0 +1,88 @@
+package scroll_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/louislef299/scroll"
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+func TestManagerStacksBuffers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	term := scrolltest.New(40, 10)
+	m := scroll.NewManager(term)
+	first := m.New(ctx, 2)
+	second := m.New(ctx, 3)
+	for _, b := range []*scroll.Buffer{first, second} {
+		b.SetIsTerm(true)
+		b.SetWidth(40)
+	}
+	first.SetPrefix("[1]")
+	second.SetPrefix("[2]")
+
+	first.Printf("a")
+	second.Printf("x")
+	first.Printf("b")
+	first.Printf("c")
+	second.Printf("y")
+
+	want := []string{"[1] b", "[1] c", "[2] x", "[2] y"}
+	if got := term.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+
+	first.NewStage("first done")
+	second.Printf("z")
+	want = []string{"first done", "[2] x", "[2] y", "[2] z"}
+	if got := term.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+
+	if err := second.Close(); err != nil {
+		t.Fatal(err)
+	}
+	first.Printf("d")
+	want = []string{"first done", "[1] d"}
+	if got := term.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+	scrolltest.AssertGolden(t, "manager", term.Golden())
+}
+
+func TestManagerConcurrentBuffers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	term := scrolltest.New(40, 20)
+	m := scroll.NewManager(term)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		b := m.New(ctx, 3)
+		b.SetIsTerm(true)
+		b.SetWidth(40)
+		b.SetHeader(true)
+
+		wg.Add(1)
+		go func(b *scroll.Buffer, n int) {
+			defer wg.Done()
+			job := b.BeginStage("job")
+			for j := 0; j < 50; j++ {
+				job.Printf("job %d line %d", n, j)
+			}
+			job.OK("job %d", n)
+		}(b, i)
+	}
+	wg.Wait()
+
+	// every job leaves its message, followed by the headers of the next stages
+	lines := term.Lines()
+	if len(lines) != 8 {
+		t.Fatalf("expected 4 messages and 4 headers, got %q", lines)
+	}
+}
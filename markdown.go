@@ -0,0 +1,84 @@
+package scroll
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ExportMarkdown writes a Markdown summary of the finished stages of the
+// Buffer to w. The summary starts with a table of the status, name and
+// duration of every stage, followed by a collapsible details block with the
+// lines of every stage that has any. Stages are listed in the order they were
+// begun, each nested stage below its parent.
+//
+// The summary must be written before the Buffer is closed, as closing it
+// discards the stage logs.
+func (b *Buffer) ExportMarkdown(w io.Writer) error {
+	stages := b.stagesByBegin()
+
+	var sb strings.Builder
+	sb.WriteString("| | Stage | Duration |\n|---|---|---|\n")
+	for _, s := range stages {
+		// nested stages are indented with spaces that aren't collapsed
+		name := strings.Repeat("&nbsp;&nbsp;", s.Depth) +
+			strings.ReplaceAll(htmlEscaper.Replace(stageName(s)), "|", `\|`)
+		fmt.Fprintf(&sb, "| %s | %s | %s |\n", markdownSymbol(s.Status), name,
+			formatDuration(s.Duration()))
+	}
+
+	for _, s := range stages {
+		if s.Log.Len() == 0 {
+			continue
+		}
+		var out strings.Builder
+		if _, err := s.Log.WriteTo(&out); err != nil {
+			return err
+		}
+		fence := "```"
+		for strings.Contains(out.String(), fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&sb, "\n<details><summary>%s %s (%s)</summary>\n\n%s\n%s%s\n\n</details>\n",
+			markdownSymbol(s.Status), htmlEscaper.Replace(stageName(s)),
+			formatDuration(s.Duration()), fence, out.String(), fence)
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteSummary appends the Markdown summary of ExportMarkdown to the file at
+// path, creating it if needed. An empty path targets the job summary of
+// GitHub Actions given by $GITHUB_STEP_SUMMARY.
+func (b *Buffer) WriteSummary(path string) error {
+	if path == "" {
+		path = os.Getenv("GITHUB_STEP_SUMMARY")
+	}
+	if path == "" {
+		return fmt.Errorf("scroll: no summary path and GITHUB_STEP_SUMMARY is not set")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := b.ExportMarkdown(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// htmlEscaper escapes text written inside HTML elements.
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// markdownSymbol returns the symbol of the Status in a summary. Stages
+// finished with NewStage are shown as successful.
+func markdownSymbol(s Status) string {
+	if s == StatusNone {
+		return StatusOK.Symbol()
+	}
+	return s.Symbol()
+}
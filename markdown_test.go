@@ -0,0 +1,45 @@
+package scroll_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/louislef299/scroll"
+)
+
+func TestWriteSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", path)
+
+	buff := scroll.NewWithRenderer(context.Background(), &eventRenderer{}, 2)
+	defer buff.Close()
+
+	deploy := buff.BeginStage("deploy")
+	build := deploy.BeginStage("build")
+	build.Printf("compiling <main>")
+	build.OK("built")
+	deploy.Skip("not on main")
+	buff.Printf("test 0")
+	buff.StageFail(errors.New("tests | failed"))
+
+	if err := buff.WriteSummary(""); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "| | Stage | Duration |\n|---|---|---|\n" +
+		"| ↷ | deploy | 0.0s |\n" +
+		"| ✔ | &nbsp;&nbsp;build | 0.0s |\n" +
+		"| ✖ | tests \\| failed | 0.0s |\n" +
+		"\n<details><summary>✔ build (0.0s)</summary>\n\n```\ncompiling <main>\n```\n\n</details>\n" +
+		"\n<details><summary>✖ tests | failed (0.0s)</summary>\n\n```\ntest 0\n```\n\n</details>\n"
+	if string(got) != want {
+		t.Fatalf("expected summary\n%s\ngot\n%s", want, got)
+	}
+}
@@ -0,0 +1,241 @@
+package scroll
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// A Policy decides what a Buffer prints when its output is not a Terminal,
+// where the scroll window can't be erased.
+type Policy int
+
+const (
+	// Passthrough prints every line and every stage message.
+	Passthrough Policy = iota
+
+	// Quiet only prints stage messages. The log of a failed stage is
+	// printed below its message.
+	Quiet
+
+	// Summary prints stage messages followed by the number of lines that
+	// were suppressed. The log of a failed stage is printed below its
+	// message.
+	Summary
+)
+
+// String returns the name of the Policy.
+func (p Policy) String() string {
+	switch p {
+	case Quiet:
+		return "quiet"
+	case Summary:
+		return "summary"
+	default:
+		return "passthrough"
+	}
+}
+
+// PlainRenderer is a Renderer for output that is not a Terminal. It never
+// writes ANSI escape sequences and prints lines according to the Policy of
+// the Window. The ANSIRenderer uses a PlainRenderer when the Window is not a
+// Terminal.
+//
+// When the Window has a CI provider and the Policy is Passthrough, every line
+// is printed as it is written inside a folded group titled with the name of
+// its stage, or "stage N" for the Nth stage begun with NewStage. The group is closed when the stage ends and followed by the stage
+// message.
+type PlainRenderer struct {
+	w io.Writer
+
+	// Represents the number of folded groups printed so far
+	groups int
+
+	// The folded group that is open, if any
+	group *ciGroup
+
+	// Represents the number of unnamed stages grouped so far and the last
+	// one, as their groups are titled by number
+	flat      int
+	flatStage stageKey
+}
+
+// A ciGroup is a folded group of output of a CI provider.
+type ciGroup struct {
+	ci             CI
+	title, section string
+
+	// The stage the group holds the lines of
+	stage stageKey
+}
+
+// A stageKey identifies a stage in the Windows and results of a Buffer.
+type stageKey struct {
+	name  string
+	depth int
+	start time.Time
+}
+
+// NewPlainRenderer creates a new PlainRenderer writing to w.
+func NewPlainRenderer(w io.Writer) *PlainRenderer {
+	return &PlainRenderer{w: w}
+}
+
+// SetOutput sets the destination output for the PlainRenderer.
+func (r *PlainRenderer) SetOutput(w io.Writer) {
+	r.w = w
+}
+
+// Line prints the line once when the Policy is Passthrough. With a CI
+// provider, the line is printed in the group of its stage.
+func (r *PlainRenderer) Line(win Window, l Line) error {
+	if folded(win) {
+		if err := r.openGroup(win); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(r.w, win.Text(l)); err != nil {
+			return err
+		}
+		return flush(r.w)
+	}
+	if win.Policy != Passthrough {
+		return nil
+	}
+	if _, err := win.lineColorWriter(l).Fprintln(r.w, win.Text(l)); err != nil {
+		return err
+	}
+	return flush(r.w)
+}
+
+// StageBegin implements Renderer. The group of a stage is opened with its
+// first line.
+func (r *PlainRenderer) StageBegin(win Window) error {
+	return nil
+}
+
+// StageEnd prints the stage message. Unless every line has already been
+// printed, a failed stage is followed by its log.
+func (r *PlainRenderer) StageEnd(win Window, s StageResult) error {
+	if folded(win) {
+		return r.endGroup(win, s)
+	}
+	if err := printStageTitle(r.w, win, s); err != nil {
+		return err
+	}
+
+	switch {
+	case s.Status == StatusFail && win.Policy != Passthrough:
+		if err := dumpStageLog(r.w, win, s); err != nil {
+			return err
+		}
+	case win.Policy == Summary && s.Log.Len() > 0:
+		msg := fmt.Sprintf("%d lines suppressed", s.Log.Len())
+		if s.Log.Len() == 1 {
+			msg = "1 line suppressed"
+		}
+		msg = indentation(s.Depth) + "  " + msg
+		if _, err := win.getColorWriter(PrinterStage).Fprintln(r.w, msg); err != nil {
+			return err
+		}
+	}
+	return flush(r.w)
+}
+
+// folded reports whether the lines of the Window are printed in folded
+// groups. Quiet and Summary don't print lines, so they have nothing to fold.
+func folded(win Window) bool {
+	return win.CI != NoCI && win.Policy == Passthrough
+}
+
+// openGroup opens the folded group of the stage of the Window, closing the
+// group of any other stage first.
+func (r *PlainRenderer) openGroup(win Window) error {
+	key := stageKey{name: win.Stage, depth: win.Depth, start: win.Start}
+	if r.group != nil && r.group.stage == key {
+		return nil
+	}
+	if err := r.closeGroup(time.Now()); err != nil {
+		return err
+	}
+
+	title := win.Stage
+	if win.Stage == "" {
+		// the message of an unnamed stage is only known once it ends
+		if key != r.flatStage {
+			r.flat++
+			r.flatStage = key
+		}
+		title = fmt.Sprintf("stage %d", r.flat)
+	}
+	title = indentation(win.Depth) + title
+	r.groups++
+	g := &ciGroup{
+		ci:      win.CI,
+		title:   title,
+		section: fmt.Sprintf("scroll_stage_%d", r.groups),
+		stage:   key,
+	}
+	if err := g.ci.openGroup(r.w, g.title, g.section, win.Start); err != nil {
+		return err
+	}
+	r.group = g
+	return nil
+}
+
+// closeGroup closes the open folded group, if any, at the end time.
+func (r *PlainRenderer) closeGroup(end time.Time) error {
+	g := r.group
+	if g == nil {
+		return nil
+	}
+	r.group = nil
+	return g.ci.closeGroup(r.w, g.title, g.section, end)
+}
+
+// endGroup closes the group of the stage and prints the stage message below
+// it. A failed stage is also reported as an error where the provider
+// supports it.
+func (r *PlainRenderer) endGroup(win Window, s StageResult) error {
+	own := r.group != nil && r.group.stage == stageKey{name: s.Name, depth: s.Depth, start: s.Start}
+	if err := r.closeGroup(s.End); err != nil {
+		return err
+	}
+	if err := printStageTitle(r.w, win, s); err != nil {
+		return err
+	}
+
+	if s.Status == StatusFail {
+		var err error
+		switch win.CI {
+		case GitHubActions:
+			_, err = fmt.Fprintf(r.w, "::error::%s\n", s.Message)
+		case AzurePipelines:
+			_, err = fmt.Fprintf(r.w, "##[error]%s\n", s.Message)
+		case Buildkite:
+			if own {
+				// expand the group of the failed stage
+				_, err = fmt.Fprintln(r.w, "^^^ +++")
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return flush(r.w)
+}
+
+// Erase implements Renderer. Printed lines can't be erased.
+func (r *PlainRenderer) Erase(win Window) error {
+	return nil
+}
+
+// Close closes the open folded group, if any.
+func (r *PlainRenderer) Close() error {
+	if r.group == nil {
+		return nil
+	}
+	if err := r.closeGroup(time.Now()); err != nil {
+		return err
+	}
+	return flush(r.w)
+}
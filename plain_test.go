@@ -0,0 +1,165 @@
+package scroll_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/louislef299/scroll"
+)
+
+// sectionTime matches the timestamp of GitLab section markers
+var sectionTime = regexp.MustCompile(`:[0-9]+:`)
+
+func TestPlainPolicies(t *testing.T) {
+	tests := []struct {
+		policy scroll.Policy
+		ci     scroll.CI
+		want   string
+	}{
+		{
+			policy: scroll.Passthrough,
+			want: "=> build 0\n=> build 1\n=> build 2\n=> build 3\n✔ built\n" +
+				"=> test 0\n✖ tests failed\n",
+		},
+		{
+			policy: scroll.Quiet,
+			want:   "✔ built\n✖ tests failed\n  => test 0\n",
+		},
+		{
+			policy: scroll.Summary,
+			want: "✔ built\n  4 lines suppressed\n" +
+				"✖ tests failed\n  => test 0\n",
+		},
+		{
+			// the Policy wins over the folding of the CI provider
+			policy: scroll.Quiet,
+			ci:     scroll.GitHubActions,
+			want:   "✔ built\n✖ tests failed\n  => test 0\n",
+		},
+		{
+			policy: scroll.Summary,
+			ci:     scroll.GitHubActions,
+			want: "✔ built\n  4 lines suppressed\n" +
+				"✖ tests failed\n  => test 0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String()+"/"+tt.ci.String(), func(t *testing.T) {
+			var out bytes.Buffer
+			buff := scroll.New(context.Background(), &out, 2)
+			defer buff.Close()
+			buff.SetIsTerm(false)
+			buff.SetCI(tt.ci)
+			buff.SetPrefix("=>")
+			buff.SetPolicy(tt.policy)
+
+			for i := 0; i < 4; i++ {
+				buff.Printf("build %d", i)
+			}
+			buff.StageOK("built")
+			buff.Printf("test 0")
+			buff.StageFail(errors.New("tests failed"))
+
+			if got := out.String(); got != tt.want {
+				t.Fatalf("expected output\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCIGroups(t *testing.T) {
+	tests := []struct {
+		ci   scroll.CI
+		want string
+	}{
+		{
+			ci: scroll.GitHubActions,
+			want: "::group::build\nbuild 0\nbuild 1\n::endgroup::\n✔ built\n" +
+				"::group::stage 1\ntest 0\n::endgroup::\n✖ tests failed\n::error::tests failed\n",
+		},
+		{
+			ci: scroll.GitLabCI,
+			want: "\033[0Ksection_start:0:scroll_stage_1[collapsed=true]\r\033[0Kbuild\n" +
+				"build 0\nbuild 1\n\033[0Ksection_end:0:scroll_stage_1\r\033[0K\n✔ built\n" +
+				"\033[0Ksection_start:0:scroll_stage_2[collapsed=true]\r\033[0Kstage 1\n" +
+				"test 0\n\033[0Ksection_end:0:scroll_stage_2\r\033[0K\n✖ tests failed\n",
+		},
+		{
+			ci: scroll.Buildkite,
+			want: "--- build\nbuild 0\nbuild 1\n✔ built\n" +
+				"--- stage 1\ntest 0\n✖ tests failed\n^^^ +++\n",
+		},
+		{
+			ci: scroll.AzurePipelines,
+			want: "##[group]build\nbuild 0\nbuild 1\n##[endgroup]\n✔ built\n" +
+				"##[group]stage 1\ntest 0\n##[endgroup]\n✖ tests failed\n##[error]tests failed\n",
+		},
+		{
+			ci: scroll.TeamCity,
+			want: "##teamcity[blockOpened name='build']\nbuild 0\nbuild 1\n##teamcity[blockClosed name='build']\n✔ built\n" +
+				"##teamcity[blockOpened name='stage 1']\ntest 0\n##teamcity[blockClosed name='stage 1']\n✖ tests failed\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ci.String(), func(t *testing.T) {
+			var out bytes.Buffer
+			buff := scroll.New(context.Background(), &out, 2)
+			defer buff.Close()
+			buff.SetIsTerm(false)
+			buff.SetCI(tt.ci)
+
+			build := buff.BeginStage("build")
+			build.Printf("build 0")
+			if !strings.Contains(out.String(), "build 0\n") {
+				t.Fatalf("expected the line to be printed as it is written, got %q", out.String())
+			}
+			build.Printf("build 1")
+			build.OK("built")
+			buff.Printf("test 0")
+			buff.StageFail(errors.New("tests failed"))
+
+			got := out.String()
+			if tt.ci == scroll.GitLabCI {
+				got = sectionTime.ReplaceAllString(got, ":0:")
+			}
+			if got != tt.want {
+				t.Fatalf("expected output\n%q\ngot\n%q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCIGroupClose(t *testing.T) {
+	var out bytes.Buffer
+	buff := scroll.New(context.Background(), &out, 2)
+	buff.SetIsTerm(false)
+	buff.SetCI(scroll.GitHubActions)
+
+	buff.Printf("hello")
+	buff.NewStage("first")
+	buff.Printf("world")
+	if err := buff.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "::group::stage 1\nhello\n::endgroup::\nfirst\n::group::stage 2\nworld\n::endgroup::\n"
+	if out.String() != want {
+		t.Fatalf("expected output\n%q\ngot\n%q", want, out.String())
+	}
+}
+
+func TestDetectCI(t *testing.T) {
+	for _, name := range []string{"GITHUB_ACTIONS", "GITLAB_CI", "BUILDKITE", "TF_BUILD", "TEAMCITY_VERSION"} {
+		t.Setenv(name, "")
+	}
+	if ci := scroll.DetectCI(); ci != scroll.NoCI {
+		t.Fatalf("expected no CI provider, got %s", ci)
+	}
+	t.Setenv("GITLAB_CI", "true")
+	if ci := scroll.DetectCI(); ci != scroll.GitLabCI {
+		t.Fatalf("expected %s, got %s", scroll.GitLabCI, ci)
+	}
+}
@@ -0,0 +1,226 @@
+package scroll
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Progress is a determinate progress bar pinned above the scroll window of
+// a Buffer. It is safe for concurrent use and is redrawn by the Buffer
+// goroutine, so it can be updated as often as needed.
+type Progress struct {
+	b *Buffer
+
+	label string
+	total int64
+	start time.Time
+
+	// States whether the progress counts bytes
+	bytes bool
+
+	current int64
+	done    bool
+
+	lock *sync.Mutex
+}
+
+// A ProgressState is a snapshot of a Progress handed to a Renderer.
+type ProgressState struct {
+	Label   string
+	Current int64
+	Total   int64
+	Bytes   bool
+	Elapsed time.Duration
+}
+
+// Progress creates a progress bar for total units of work that is pinned
+// above the scroll window until Done is called. A total of zero or less shows
+// the progress without a percentage.
+func (b *Buffer) Progress(total int64, label string) *Progress {
+	return b.newProgress(total, label, false)
+}
+
+// newProgress creates a progress bar and pins it to the Buffer.
+func (b *Buffer) newProgress(total int64, label string, bytes bool) *Progress {
+	p := &Progress{
+		b:     b,
+		label: label,
+		total: total,
+		bytes: bytes,
+		start: time.Now(),
+		lock:  &sync.Mutex{},
+	}
+	b.progresser <- p
+	<-b.done
+	return p
+}
+
+// Add adds n to the progress.
+func (p *Progress) Add(n int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.current += n
+}
+
+// Set sets the progress to n.
+func (p *Progress) Set(n int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.current = n
+}
+
+// Current returns the current progress.
+func (p *Progress) Current() int64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.current
+}
+
+// Done removes the progress bar from the Buffer and writes its final state
+// to the scroll window. Calling Done more than once has no effect.
+func (p *Progress) Done() {
+	p.lock.Lock()
+	if p.done {
+		p.lock.Unlock()
+		return
+	}
+	p.done = true
+	p.lock.Unlock()
+
+	p.b.progresser <- p
+	<-p.b.done
+}
+
+// state returns a snapshot of the progress.
+func (p *Progress) state() ProgressState {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return ProgressState{
+		Label:   p.label,
+		Current: p.current,
+		Total:   p.total,
+		Bytes:   p.bytes,
+		Elapsed: time.Since(p.start),
+	}
+}
+
+// Rate returns the progress per second.
+func (s ProgressState) Rate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Current) / s.Elapsed.Seconds()
+}
+
+// ETA returns the estimated time left, or false if it is unknown.
+func (s ProgressState) ETA() (time.Duration, bool) {
+	rate := s.Rate()
+	if s.Total <= 0 || rate <= 0 {
+		return 0, false
+	}
+	left := float64(s.Total-s.Current) / rate
+	if left < 0 {
+		left = 0
+	}
+	return time.Duration(left * float64(time.Second)), true
+}
+
+// Text renders the progress as a bar with its percentage, rate and ETA that
+// fits in the width.
+func (s ProgressState) Text(width int) string {
+	var info []string
+	if s.Total > 0 {
+		info = append(info, fmt.Sprintf("%3d%%", s.percent()))
+		info = append(info, s.amount(s.Current)+"/"+s.amount(s.Total))
+	} else {
+		info = append(info, s.amount(s.Current))
+	}
+	info = append(info, s.amount(int64(s.Rate()))+"/s")
+	if eta, ok := s.ETA(); ok {
+		info = append(info, "ETA "+formatDuration(eta))
+	}
+	suffix := strings.Join(info, " ")
+
+	// label [bar] suffix
+	barWidth := width - len([]rune(s.Label)) - len(suffix) - 4
+	if barWidth > 40 {
+		barWidth = 40
+	}
+	if s.Total <= 0 || barWidth < 5 {
+		return strings.TrimSpace(s.Label + " " + suffix)
+	}
+	filled := barWidth * s.percent() / 100
+	bar := strings.Repeat("=", filled)
+	if filled < barWidth {
+		bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s [%s] %s", s.Label, bar, suffix))
+}
+
+// percent returns the completed percentage clamped to 0-100.
+func (s ProgressState) percent() int {
+	if s.Total <= 0 {
+		return 0
+	}
+	p := int(s.Current * 100 / s.Total)
+	if p < 0 {
+		return 0
+	}
+	if p > 100 {
+		return 100
+	}
+	return p
+}
+
+// amount formats a number of units of the progress.
+func (s ProgressState) amount(n int64) string {
+	if s.Bytes {
+		return formatBytes(n)
+	}
+	return fmt.Sprint(n)
+}
+
+// formatBytes formats a number of bytes in binary units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// pin adds a progress bar to the Buffer, or removes it and prints its final
+// state once it is done.
+func (b *Buffer) pin(p *Progress) {
+	p.lock.Lock()
+	done := p.done
+	p.lock.Unlock()
+
+	if !done {
+		b.bars = append(b.bars, p)
+		b.redraw()
+		return
+	}
+	for i, bar := range b.bars {
+		if bar == p {
+			b.bars = append(b.bars[:i:i], b.bars[i+1:]...)
+			break
+		}
+	}
+	b.print(lineRequest{text: p.state().Text(b.window().width())})
+}
+
+// redraw hands the Window to the renderer to redraw the header and progress
+// bars.
+func (b *Buffer) redraw() {
+	if r, ok := b.renderer.(Refresher); ok {
+		b.setErr(r.Refresh(b.window()))
+	}
+}
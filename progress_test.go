@@ -0,0 +1,118 @@
+package scroll_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/louislef299/scroll"
+)
+
+func TestProgressStateText(t *testing.T) {
+	tests := []struct {
+		state scroll.ProgressState
+		width int
+		want  string
+	}{
+		{
+			state: scroll.ProgressState{Label: "chunks", Current: 25, Total: 100, Elapsed: 5 * time.Second},
+			width: 60,
+			want:  "chunks [======>                  ]  25% 25/100 5/s ETA 15.0s",
+		},
+		{
+			state: scroll.ProgressState{Label: "image", Current: 3 << 20, Total: 4 << 20, Bytes: true, Elapsed: 3 * time.Second},
+			width: 80,
+			want:  "image [========================>       ]  75% 3.0 MiB/4.0 MiB 1.0 MiB/s ETA 1.0s",
+		},
+		{
+			state: scroll.ProgressState{Label: "unknown", Current: 10, Elapsed: time.Second},
+			width: 60,
+			want:  "unknown 10 10/s",
+		},
+		{
+			state: scroll.ProgressState{Label: "narrow", Current: 1, Total: 2, Elapsed: time.Second},
+			width: 20,
+			want:  "narrow  50% 1/2 1/s ETA 1.0s",
+		},
+	}
+	for _, tt := range tests {
+		got := tt.state.Text(tt.width)
+		if got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+		if n := len([]rune(got)); n > tt.width && strings.Contains(got, "[") {
+			t.Errorf("expected a bar of %d columns to fit in %d", n, tt.width)
+		}
+	}
+}
+
+func TestProgressPinned(t *testing.T) {
+	buff, term := newTermBuffer(t, 60, 10, 2)
+
+	p := buff.Progress(100, "download")
+	buff.Printf("got chunk 1")
+	buff.Printf("got chunk 2")
+	buff.Printf("got chunk 3")
+
+	screen := term.Screen()
+	if !strings.HasPrefix(screen[0], "download [>") || !strings.Contains(screen[0], "  0% 0/100") {
+		t.Fatalf("expected an empty progress bar, got %q", screen[0])
+	}
+	if screen[1] != "got chunk 2" || screen[2] != "got chunk 3" || screen[3] != "" {
+		t.Fatalf("expected the scroll window below the bar, got %q", screen)
+	}
+
+	p.Add(40)
+	p.Add(10)
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(term.Screen()[0], " 50% 50/100") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the bar to be redrawn, got %q", term.Screen())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	p.Set(100)
+	p.Done()
+	p.Done()
+	screen = term.Screen()
+	if screen[0] != "got chunk 3" || !strings.Contains(screen[1], "100% 100/100") || screen[2] != "" {
+		t.Fatalf("expected the finished bar in the scroll window, got %q", screen)
+	}
+}
+
+func TestProgressReaderWriter(t *testing.T) {
+	buff, term := newTermBuffer(t, 80, 10, 3)
+
+	data := strings.Repeat("x", 3<<10)
+	r := scroll.ProgressReader(buff, strings.NewReader(data), int64(len(data)), "download")
+	var sb strings.Builder
+	w := scroll.ProgressWriter(buff, &sb, int64(len(data)), "upload")
+
+	screen := term.Screen()
+	if !strings.HasPrefix(screen[0], "download [") || !strings.HasPrefix(screen[1], "upload [") {
+		t.Fatalf("expected both bars to be pinned, got %q", screen)
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != data {
+		t.Fatal("expected the data to be copied unchanged")
+	}
+
+	screen = term.Screen()
+	if !strings.HasPrefix(screen[0], "download [") || !strings.Contains(screen[0], "100% 3.0 KiB/3.0 KiB") {
+		t.Fatalf("expected the finished download in the scroll window, got %q", screen[0])
+	}
+	if !strings.HasPrefix(screen[1], "upload [") || screen[2] != "" {
+		t.Fatalf("expected the finished upload in the scroll window, got %q", screen)
+	}
+}
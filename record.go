@@ -0,0 +1,156 @@
+package scroll
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// A Recorder is an io.Writer that passes everything written to it on to an
+// output while recording it with timestamps as an asciicast v2 file, which
+// can be played back with asciinema. Use a Recorder as the output of a Buffer
+// to record its session:
+//
+//	rec := scroll.NewRecorder(os.Stdout, castFile)
+//	buff := scroll.New(ctx, rec, 5)
+//
+// Line feeds are recorded as a carriage return and line feed, like a
+// terminal prints them. A Recorder is safe for concurrent use.
+type Recorder struct {
+	w    io.Writer
+	cast io.Writer
+
+	// The size of the recorded terminal, written with the header
+	width, height int
+
+	start  time.Time
+	header bool
+
+	// The bytes of an incomplete UTF-8 sequence at the end of the last write
+	pending []byte
+
+	lock *sync.Mutex
+}
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// NewRecorder creates a new Recorder writing to w and recording to cast. The
+// recorded terminal size defaults to the size of stdout, or 80x24 if stdout
+// is not a Terminal.
+func NewRecorder(w, cast io.Writer) *Recorder {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+	return &Recorder{
+		w:      w,
+		cast:   cast,
+		width:  width,
+		height: height,
+		start:  time.Now(),
+		lock:   &sync.Mutex{},
+	}
+}
+
+// SetSize sets the recorded terminal size. It has no effect once something
+// has been written.
+func (r *Recorder) SetSize(width, height int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.width, r.height = width, height
+}
+
+// Write writes p to the output and records it as an output event.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	n, err := r.w.Write(p)
+	if recErr := r.record(p[:n]); err == nil {
+		err = recErr
+	}
+	return n, err
+}
+
+// Flush flushes the output if it supports flushing.
+func (r *Recorder) Flush() error {
+	return flush(r.w)
+}
+
+// Close records any incomplete UTF-8 sequence that is still pending. The
+// output and the cast are not closed.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.pending) == 0 {
+		return nil
+	}
+	data := string(r.pending)
+	r.pending = nil
+	return r.event(data)
+}
+
+// record writes the bytes as an output event, holding back an incomplete
+// UTF-8 sequence at the end until the next write.
+func (r *Recorder) record(p []byte) error {
+	data := append(r.pending, p...)
+	r.pending = nil
+
+	// a rune is at most 4 bytes, so only the last 3 can be incomplete
+	for i := len(data) - 1; i >= 0 && i >= len(data)-3; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				r.pending = append([]byte(nil), data[i:]...)
+				data = data[:i]
+			}
+			break
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return r.event(string(data))
+}
+
+// event writes an output event, preceded by the header on the first call.
+func (r *Recorder) event(data string) error {
+	if !r.header {
+		r.header = true
+		b, err := json.Marshal(castHeader{
+			Version:   2,
+			Width:     r.width,
+			Height:    r.height,
+			Timestamp: r.start.Unix(),
+			Env:       map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(r.cast, "%s\n", b); err != nil {
+			return err
+		}
+	}
+
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\n", "\r\n")
+	b, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), "o", data})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.cast, "%s\n", b)
+	return err
+}
@@ -0,0 +1,77 @@
+package scroll_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/louislef299/scroll"
+)
+
+func TestRecorder(t *testing.T) {
+	var out, cast bytes.Buffer
+	rec := scroll.NewRecorder(&out, &cast)
+	rec.SetSize(40, 10)
+
+	buff := scroll.New(context.Background(), rec, 2)
+	buff.SetIsTerm(true)
+	buff.SetWidth(40)
+	buff.Printf("héllo")
+	buff.NewStage("done")
+	if err := buff.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// split a rune across two writes
+	snowman := []byte("☃\n")
+	rec.Write(snowman[:1])
+	rec.Write(snowman[1:])
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := bufio.NewScanner(&cast)
+	if !s.Scan() {
+		t.Fatal("expected a header")
+	}
+	var header struct {
+		Version int `json:"version"`
+		Width   int `json:"width"`
+		Height  int `json:"height"`
+	}
+	if err := json.Unmarshal(s.Bytes(), &header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != 2 || header.Width != 40 || header.Height != 10 {
+		t.Fatalf("unexpected header %s", s.Text())
+	}
+
+	var recorded strings.Builder
+	last := 0.0
+	for s.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(s.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		if len(event) != 3 || event[1] != "o" {
+			t.Fatalf("unexpected event %s", s.Text())
+		}
+		if at := event[0].(float64); at < last {
+			t.Fatalf("event %s is older than %f", s.Text(), last)
+		} else {
+			last = at
+		}
+		recorded.WriteString(event[2].(string))
+	}
+
+	want := strings.ReplaceAll(out.String(), "\n", "\r\n")
+	if got := recorded.String(); got != want {
+		t.Fatalf("expected recording\n%q\ngot\n%q", want, got)
+	}
+	if !strings.HasSuffix(want, "☃\r\n") {
+		t.Fatalf("expected output to end with the snowman, got %q", want)
+	}
+}
@@ -0,0 +1,242 @@
+package scroll
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+// A Renderer draws the output of a Buffer. The Buffer keeps track of its lines
+// and stages and calls the Renderer from a single goroutine, so a Renderer
+// does not need to be safe for concurrent use.
+type Renderer interface {
+	// Line is called for every line written to the Buffer. The Window already
+	// includes the new line.
+	Line(win Window, l Line) error
+
+	// StageBegin is called when a named stage is begun with BeginStage. The
+	// Window belongs to the new stage.
+	StageBegin(win Window) error
+
+	// StageEnd is called when a stage is finished with NewStage or one of the
+	// stage outcomes. The Window belongs to the stage that continues.
+	StageEnd(win Window, s StageResult) error
+
+	// Erase is called when the lines of the Window are erased without
+	// finishing the stage.
+	Erase(win Window) error
+
+	// Close is called once when the Buffer is closed or its context is done.
+	Close() error
+}
+
+// A Line is a single line of output written to a Buffer.
+type Line struct {
+	Text string
+	Time time.Time
+
+	// States whether the line was written to stderr by a command run with
+	// Buffer.Run
+	Stderr bool
+}
+
+// A Window is a snapshot of the scroll window and settings of a Buffer that is
+// handed to its Renderer with every event.
+type Window struct {
+	// The most recent lines of the current stage, oldest first
+	Lines []Line
+
+	// The name, depth and start of the current stage
+	Stage string
+	Depth int
+	Start time.Time
+
+	// States whether the stage header is shown above the scroll window
+	Header bool
+
+	// The progress bars pinned above the scroll window
+	Progress []ProgressState
+
+	// The max length of the visible output to the user
+	Size int
+
+	// A prefix to print before each line
+	Prefix string
+
+	// The color of scrolling output, stage finalizer output and lines
+	// written to stderr
+	PrinterColor color.Attribute
+	StageColor   color.Attribute
+	StderrColor  color.Attribute
+
+	// States whether the output is a Terminal
+	IsTerm bool
+
+	// The terminal width used to wrap lines, zero detects it from stdout
+	Width int
+
+	// What to print when the output is not a Terminal
+	Policy Policy
+
+	// The CI provider whose folding markers wrap each stage when the output
+	// is not a Terminal
+	CI CI
+}
+
+// Text returns the text of l as it should be printed, including the prefix
+// and the indentation of the stage.
+func (win Window) Text(l Line) string {
+	return win.text(l, win.Depth)
+}
+
+// text returns the text of l as it should be printed for a stage at the
+// depth.
+func (win Window) text(l Line, depth int) string {
+	return indentation(depth) +
+		strings.TrimSpace(strings.Join([]string{win.Prefix, l.Text}, " "))
+}
+
+// indentation returns the indentation of a stage at the depth.
+func indentation(depth int) string {
+	return strings.Repeat("  ", depth)
+}
+
+// Rows returns the lines of the Window split to the terminal width, keeping
+// at most Size rows.
+func (win Window) Rows() []string {
+	var rows []string
+	for _, r := range win.rows() {
+		rows = append(rows, r.text)
+	}
+	return rows
+}
+
+// rows returns the rows of the Window like Rows, each with the color of its
+// line.
+func (win Window) rows() []coloredRow {
+	var rows []coloredRow
+	for _, l := range win.Lines {
+		c := win.lineColorWriter(l)
+		for _, s := range chunk(win.Text(l), win.width()) {
+			rows = append(rows, coloredRow{text: s, color: c})
+		}
+	}
+	if len(rows) > win.Size {
+		rows = rows[len(rows)-win.Size:]
+	}
+	return rows
+}
+
+// width returns the width of the Window or the width of stdout.
+func (win Window) width() int {
+	if win.Width > 0 {
+		return win.Width
+	}
+	return getBufferSize()
+}
+
+// getColorWriter gets the color set in the Window based on the stage.
+func (win Window) getColorWriter(s stage) *color.Color {
+	switch s {
+	case EraserStage:
+		return win.colorWriter(win.StageColor)
+	default:
+		return win.colorWriter(win.PrinterColor)
+	}
+}
+
+// lineColorWriter gets the color of the line set in the Window, which is the
+// stderr color for lines written to stderr.
+func (win Window) lineColorWriter(l Line) *color.Color {
+	if l.Stderr {
+		return win.colorWriter(win.StderrColor)
+	}
+	return win.getColorWriter(PrinterStage)
+}
+
+// getStatusWriter gets the color for the message of a stage with the Status,
+// falling back to the stage color set in the Window.
+func (win Window) getStatusWriter(s Status) *color.Color {
+	if c, ok := s.Color(); ok {
+		return win.colorWriter(c)
+	}
+	return win.getColorWriter(EraserStage)
+}
+
+// colorWriter returns a color writer that only writes colors to a Terminal.
+func (win Window) colorWriter(c color.Attribute) *color.Color {
+	cw := color.New(c)
+	if win.IsTerm && !noColor() {
+		cw.EnableColor()
+	} else {
+		cw.DisableColor()
+	}
+	return cw
+}
+
+// Custom stage type for color function
+type stage string
+
+const (
+	PrinterStage stage = "PRINTER"
+	EraserStage  stage = "ERASER"
+)
+
+// noColor reports whether the user opted out of colors with the NO_COLOR
+// environment variable.
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+func getBufferSize() int {
+	// dynamically checks to see if the buffer will go beyond the width limit of
+	// the terminal
+	w, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return DEFAULT_BUFFER_SIZE
+	}
+	return w
+}
+
+// chunk splits a provided string by newline characters and by the maximum
+// length of the buffer. A string list of plaintext strings that fit in each
+// buffer line is returned.
+func chunk(s string, chunkSize int) []string {
+	if len(s) == 0 {
+		return nil
+	}
+
+	splits := strings.Split(s, "\n")
+	if chunkSize >= len(s) {
+		return splits
+	}
+	var chunks []string
+
+	for _, split := range splits {
+		currentLen := 0
+		currentStart := 0
+		for i := range split {
+			if currentLen == chunkSize {
+				chunks = append(chunks, split[currentStart:i])
+				currentLen = 0
+				currentStart = i
+			}
+			currentLen++
+		}
+		chunks = append(chunks, split[currentStart:])
+	}
+	return chunks
+}
+
+// flush flushes w if it is buffered, such as a bufio.Writer, so that each
+// event is shown on screen as a whole.
+func flush(w io.Writer) error {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
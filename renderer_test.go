@@ -0,0 +1,81 @@
+package scroll_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/louislef299/scroll"
+)
+
+// eventRenderer records the events it receives as strings.
+type eventRenderer struct {
+	events []string
+	err    error
+}
+
+func (r *eventRenderer) Line(win scroll.Window, l scroll.Line) error {
+	r.events = append(r.events, fmt.Sprintf("line %q window=%d", win.Text(l), len(win.Lines)))
+	return r.err
+}
+
+func (r *eventRenderer) StageBegin(win scroll.Window) error {
+	r.events = append(r.events, fmt.Sprintf("begin %q depth=%d", win.Stage, win.Depth))
+	return r.err
+}
+
+func (r *eventRenderer) StageEnd(win scroll.Window, s scroll.StageResult) error {
+	r.events = append(r.events, fmt.Sprintf("stage %q window=%d", s.Message, len(win.Lines)))
+	return r.err
+}
+
+func (r *eventRenderer) Erase(win scroll.Window) error {
+	r.events = append(r.events, "erase")
+	return r.err
+}
+
+func (r *eventRenderer) Close() error {
+	r.events = append(r.events, "close")
+	return nil
+}
+
+func TestRendererEvents(t *testing.T) {
+	r := &eventRenderer{}
+	buff := scroll.NewWithRenderer(context.Background(), r, 2)
+	buff.SetPrefix("=>")
+
+	buff.Printf("one")
+	buff.Printf("two")
+	buff.Printf("three")
+	buff.EraseBuffer()
+	buff.Println("four")
+	buff.NewStage("done")
+	if err := buff.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`line "=> one" window=1`,
+		`line "=> two" window=2`,
+		`line "=> three" window=2`,
+		"erase",
+		`line "=> four" window=1`,
+		`stage "done" window=0`,
+		"close",
+	}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("expected events %q, got %q", want, r.events)
+	}
+}
+
+func TestRendererError(t *testing.T) {
+	errRender := errors.New("render failed")
+	buff := scroll.NewWithRenderer(context.Background(), &eventRenderer{err: errRender}, 2)
+
+	buff.Printf("one")
+	if err := buff.Close(); !errors.Is(err, errRender) {
+		t.Fatalf("expected %v, got %v", errRender, err)
+	}
+}
@@ -0,0 +1,144 @@
+package scroll
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Run starts the command with its stdout and stderr printed line by line to
+// the current stage, lines written to stderr in the stderr color, and waits
+// for it to exit. The stage is then finished as successful, or as failed with
+// every line the command wrote when it exits with an error. The command is
+// killed when the context is done. The Stdout and Stderr of the command must
+// be nil.
+//
+// The error is that of cmd.Wait, wrapped with the command line, so an
+// *exec.ExitError can be retrieved with errors.As. When the context of the
+// Buffer is done the stage is left unfinished.
+func (b *Buffer) Run(ctx context.Context, cmd *exec.Cmd) error {
+	b.lock.RLock()
+	st := b.current()
+	b.lock.RUnlock()
+	cmdline := strings.Join(cmd.Args, " ")
+
+	err := b.exec(ctx, cmd, st)
+	if b.ctx.Err() != nil {
+		// the Buffer closes with its context, so the stage can't be finished
+		if err == nil {
+			err = b.ctx.Err()
+		}
+		return fmt.Errorf("%s: %w", cmdline, err)
+	}
+	if err == nil {
+		msg := ""
+		if st.name == "" {
+			msg = cmdline
+		}
+		b.finishStage(st, StageResult{Message: msg, Status: StatusOK})
+		return nil
+	}
+	err = fmt.Errorf("%s: %w", cmdline, err)
+	b.finishStage(st, StageResult{Message: err.Error(), Status: StatusFail, Err: err})
+	return err
+}
+
+// Exec runs the command like Run, but leaves the current stage open so it
+// can be finished with an outcome of the caller's choosing. The error is that
+// of cmd.Wait.
+func (b *Buffer) Exec(ctx context.Context, cmd *exec.Cmd) error {
+	b.lock.RLock()
+	st := b.current()
+	b.lock.RUnlock()
+	return b.exec(ctx, cmd, st)
+}
+
+// Exec runs the command like Run with its output printed to the Stage, but
+// leaves the Stage open. The error is that of cmd.Wait.
+func (s *Stage) Exec(ctx context.Context, cmd *exec.Cmd) error {
+	return s.b.exec(ctx, cmd, s.state)
+}
+
+// exec runs the command with its output printed to the stage. Once the
+// command exited, its children get a WaitDelay of a second, unless one is
+// set, to close its output before Wait gives up on them.
+func (b *Buffer) exec(ctx context.Context, cmd *exec.Cmd, st *stageState) error {
+	if cmd.Stdout != nil || cmd.Stderr != nil {
+		return errors.New("scroll: Stdout or Stderr already set")
+	}
+	stdout := &lineWriter{b: b, stage: st}
+	stderr := &lineWriter{b: b, stage: st, stderr: true}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = time.Second
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cmd.Process.Kill()
+		case <-exited:
+		}
+	}()
+
+	err := cmd.Wait()
+	close(exited)
+	stdout.flush()
+	stderr.flush()
+
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// the command succeeded, a child it left behind kept its output open
+		err = nil
+	}
+	if err != nil && ctx.Err() != nil {
+		err = fmt.Errorf("%w (%w)", err, ctx.Err())
+	}
+	return err
+}
+
+// A lineWriter prints what the command writes to one of its pipes to the
+// stage line by line. Each pipe is copied by a single goroutine, so it needs
+// no locking.
+type lineWriter struct {
+	b      *Buffer
+	stage  *stageState
+	stderr bool
+
+	// An incomplete line waiting for its end
+	partial []byte
+}
+
+// Write prints every complete line of p and keeps the rest.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.print(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush prints an incomplete last line.
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.print(string(w.partial))
+		w.partial = nil
+	}
+}
+
+// print prints a single line without its carriage return.
+func (w *lineWriter) print(text string) {
+	w.b.printTo(lineRequest{text: strings.TrimRight(text, "\r"), stage: w.stage, stderr: w.stderr})
+}
@@ -0,0 +1,126 @@
+package scroll_test
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/louislef299/scroll"
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+func TestRun(t *testing.T) {
+	buff, term := newTermBuffer(t, 60, 12, 3)
+
+	err := buff.Run(context.Background(), exec.Command("sh", "-c", "echo out; printf partial"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := term.Lines(); len(got) != 1 || got[0] != "✔ sh -c echo out; printf partial" {
+		t.Fatalf("expected the command line as the stage message, got %q", got)
+	}
+
+	st := buff.BeginStage("build")
+	if err := buff.Run(context.Background(), exec.Command("true")); err != nil {
+		t.Fatal(err)
+	}
+	if got := buff.Stages(); got[len(got)-1].Name != st.Name() || got[len(got)-1].Message != "build" {
+		t.Fatalf("expected the named stage to end with its name, got %+v", got[len(got)-1])
+	}
+}
+
+func TestRunFail(t *testing.T) {
+	buff, term := newTermBuffer(t, 80, 12, 3)
+	buff.SetStderrColor(color.FgYellow)
+
+	err := buff.Run(context.Background(), exec.Command("sh", "-c", "echo out; echo err >&2; printf partial; exit 3"))
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || exit.ExitCode() != 3 {
+		t.Fatalf("expected exit status 3, got %v", err)
+	}
+
+	lines := term.Lines()
+	if len(lines) != 4 || lines[0] != "✖ sh -c echo out; echo err >&2; printf partial; exit 3: exit status 3" {
+		t.Fatalf("expected the failure and every line, got %q", lines)
+	}
+	dumped := append([]string(nil), lines[1:]...)
+	sort.Strings(dumped)
+	if strings.Join(dumped, ",") != "  err,  out,  partial" {
+		t.Fatalf("expected the lines of both pipes, got %q", lines[1:])
+	}
+
+	for i, l := range lines[1:] {
+		want := ""
+		if l == "  err" {
+			want = "33"
+		}
+		if got := term.Row(i + 1)[2].Attr.FG; got != want {
+			t.Fatalf("expected color %q for %q, got %q", want, l, got)
+		}
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	buff, _ := newTermBuffer(t, 60, 12, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := buff.Run(ctx, exec.Command("sleep", "10"))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the command to be canceled, got %v", err)
+	}
+}
+
+func TestRunOrphan(t *testing.T) {
+	buff, term := newTermBuffer(t, 80, 12, 3)
+
+	// the background sleep keeps the output of the killed shell open
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := buff.Run(ctx, exec.Command("sh", "-c", "sleep 30 & echo hi; sleep 30"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the command to time out, got %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("expected Run to return after the wait delay, took %s", d)
+	}
+	if got := term.Lines(); len(got) != 2 || got[1] != "  hi" {
+		t.Fatalf("expected the output of the command, got %q", got)
+	}
+
+	if err := buff.Run(context.Background(), exec.Command("sh", "-c", "sleep 30 & echo hi")); err != nil {
+		t.Fatalf("expected a command leaving a child behind to succeed, got %v", err)
+	}
+}
+
+func TestRunBufferCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	buff := scroll.New(ctx, scrolltest.New(60, 12), 3)
+	buff.SetIsTerm(true)
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- buff.Run(ctx, exec.Command("sh", "-c", "while true; do echo hi; sleep 0.01; done"))
+	}()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the command to be canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Run to return once the Buffer is canceled")
+	}
+	buff.Printf("after close")
+	buff.NewStage("after close")
+	if err := buff.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,57 @@
+package scrolltest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The flag is namespaced so test packages importing scrolltest can define
+// their own -update flag, which is honored as well.
+var update = flag.Bool("scrolltest.update", false, "update scrolltest golden files")
+
+// updating reports whether golden files should be rewritten.
+func updating() bool {
+	if *update {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	g, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	on, _ := g.Get().(bool)
+	return on
+}
+
+// AssertGolden compares got against testdata/<name>.golden and fails the test
+// when they differ. Running the tests with -scrolltest.update, or with an
+// -update flag defined by the test package, rewrites the golden file instead.
+func AssertGolden(t testing.TB, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -scrolltest.update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s (run with -scrolltest.update to regenerate)\n--- got ---\n%s\n--- want ---\n%s",
+			path, got, want)
+	}
+}
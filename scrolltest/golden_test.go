@@ -0,0 +1,34 @@
+package scrolltest_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+// A test package's own -update flag, which scrolltest must not redefine
+var update = flag.Bool("update", false, "update golden files")
+
+func TestAssertGoldenUpdate(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	*update = true
+	scrolltest.AssertGolden(t, "frame", []byte("hello\n"))
+	*update = false
+
+	got, err := os.ReadFile(filepath.Join("testdata", "frame.golden"))
+	if err != nil || string(got) != "hello\n" {
+		t.Fatalf("expected the golden file to be written with -update, got %q, %v", got, err)
+	}
+	scrolltest.AssertGolden(t, "frame", []byte("hello\n"))
+}
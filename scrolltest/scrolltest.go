@@ -0,0 +1,31 @@
+// Package scrolltest provides a headless virtual terminal for asserting what a
+// scroll Buffer would show to a user.
+//
+// A Terminal understands the small subset of VT100/ANSI sequences the scroll
+// package emits (cursor movement, line and display erasure and SGR colors),
+// so tests can check the visible screen and the scrollback after every stage
+// instead of watching real terminal output.
+package scrolltest
+
+import "github.com/louislef299/scroll/internal/vt"
+
+// A Terminal is an in-memory VT100 emulator that implements io.Writer. Line
+// feeds are treated as a carriage return plus line feed, matching a tty with
+// output post-processing enabled, and every rune occupies a single column.
+type Terminal = vt.Terminal
+
+// A Cell is a single character position on the screen.
+type Cell = vt.Cell
+
+// Attr describes the SGR attributes of a single Cell. Colors are stored as
+// their SGR parameters, e.g. "31", "95" or "38;5;208", and are empty when the
+// default color is in use.
+type Attr = vt.Attr
+
+// A Frame is a snapshot of the visible screen of a Terminal.
+type Frame = vt.Frame
+
+// New creates a new Terminal with the given screen width and height.
+func New(width, height int) *Terminal {
+	return vt.New(width, height)
+}
@@ -0,0 +1,103 @@
+package scrolltest_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+func TestTerminalWrite(t *testing.T) {
+	term := scrolltest.New(10, 3)
+	fmt.Fprint(term, "hello\nworld\n")
+
+	want := []string{"hello", "world", ""}
+	if got := term.Screen(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected screen %q, got %q", want, got)
+	}
+	if x, y := term.Cursor(); x != 0 || y != 2 {
+		t.Fatalf("expected cursor at 0,2, got %d,%d", x, y)
+	}
+}
+
+func TestTerminalScrollback(t *testing.T) {
+	term := scrolltest.New(10, 2)
+	for i := 0; i < 4; i++ {
+		fmt.Fprintf(term, "line %d\n", i)
+	}
+
+	if got, want := term.Scrollback(), []string{"line 0", "line 1", "line 2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected scrollback %q, got %q", want, got)
+	}
+	if got, want := term.Lines(), []string{"line 0", "line 1", "line 2", "line 3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+}
+
+func TestTerminalEraseLines(t *testing.T) {
+	term := scrolltest.New(10, 5)
+	fmt.Fprint(term, "keep\none\ntwo\n")
+	// the sequences emitted by cursorUp and clearEntireLine
+	fmt.Fprint(term, "\033[1A\033[2K\033[1A\033[2K")
+	fmt.Fprint(term, "three\n")
+
+	want := []string{"keep", "three", "", "", ""}
+	if got := term.Screen(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected screen %q, got %q", want, got)
+	}
+}
+
+func TestTerminalWrap(t *testing.T) {
+	term := scrolltest.New(4, 3)
+	fmt.Fprint(term, "abcdefg\n")
+
+	want := []string{"abcd", "efg", ""}
+	if got := term.Screen(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected screen %q, got %q", want, got)
+	}
+
+	// writing exactly the width must not produce a blank line
+	term = scrolltest.New(4, 3)
+	fmt.Fprint(term, "abcd\nef\n")
+	want = []string{"abcd", "ef", ""}
+	if got := term.Screen(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected screen %q, got %q", want, got)
+	}
+}
+
+func TestTerminalSGR(t *testing.T) {
+	term := scrolltest.New(10, 1)
+	fmt.Fprint(term, "\033[1;95mab\033[0mc\033[38;5;208md")
+
+	row := term.Row(0)
+	tests := []struct {
+		cell int
+		want scrolltest.Attr
+	}{
+		{0, scrolltest.Attr{FG: "95", Bold: true}},
+		{1, scrolltest.Attr{FG: "95", Bold: true}},
+		{2, scrolltest.Attr{}},
+		{3, scrolltest.Attr{FG: "38;5;208"}},
+	}
+	for _, tt := range tests {
+		if got := row[tt.cell].Attr; got != tt.want {
+			t.Errorf("cell %d: expected %q, got %q", tt.cell, tt.want, got)
+		}
+	}
+}
+
+func TestTerminalSplitWrites(t *testing.T) {
+	term := scrolltest.New(10, 1)
+	seq := []byte("\033[31m✔ ok")
+	for i := range seq {
+		term.Write(seq[i : i+1])
+	}
+
+	if got := term.String(); got != "✔ ok" {
+		t.Fatalf("expected %q, got %q", "✔ ok", got)
+	}
+	if got := term.Row(0)[0].Attr.FG; got != "31" {
+		t.Fatalf("expected fg 31, got %q", got)
+	}
+}
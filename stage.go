@@ -0,0 +1,309 @@
+package scroll
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// A Status is the outcome of a stage.
+type Status int
+
+const (
+	// StatusNone is the outcome of a stage finished with NewStage.
+	StatusNone Status = iota
+	StatusOK
+	StatusFail
+	StatusWarn
+	StatusSkip
+)
+
+// String returns the name of the Status.
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusFail:
+		return "fail"
+	case StatusWarn:
+		return "warn"
+	case StatusSkip:
+		return "skip"
+	default:
+		return "none"
+	}
+}
+
+// Symbol returns the symbol printed before the message of a stage with the
+// Status. StatusNone has no symbol.
+func (s Status) Symbol() string {
+	switch s {
+	case StatusOK:
+		return "✔"
+	case StatusFail:
+		return "✖"
+	case StatusWarn:
+		return "⚠"
+	case StatusSkip:
+		return "↷"
+	default:
+		return ""
+	}
+}
+
+// Color returns the color used for the message of a stage with the Status.
+// The ok is false for StatusNone, which uses the stage color of the Buffer.
+func (s Status) Color() (c color.Attribute, ok bool) {
+	switch s {
+	case StatusOK:
+		return color.FgGreen, true
+	case StatusFail:
+		return color.FgRed, true
+	case StatusWarn:
+		return color.FgYellow, true
+	case StatusSkip:
+		return color.FgHiBlack, true
+	default:
+		return 0, false
+	}
+}
+
+// A StageResult describes a finished stage of a Buffer.
+type StageResult struct {
+	// The name given to BeginStage, empty for stages finished with NewStage
+	Name string
+
+	// The number of named stages the stage is nested in
+	Depth int
+
+	// The message the stage was finished with
+	Message string
+	Status  Status
+
+	// The error passed to StageFail
+	Err error
+
+	// Every line written during the stage
+	Log *Log
+
+	Start time.Time
+	End   time.Time
+
+	// The lines printed below a failed stage instead of its whole Log, if
+	// set. It is only valid while the stage is being rendered.
+	dump *Log
+}
+
+// Duration returns how long the stage ran.
+func (s StageResult) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Title returns the message of the stage prefixed with the symbol of its
+// Status.
+func (s StageResult) Title() string {
+	if sym := s.Status.Symbol(); sym != "" {
+		return sym + " " + s.Message
+	}
+	return s.Message
+}
+
+// StageOK finishes the current stage as successful and prints the message.
+func (b *Buffer) StageOK(format string, a ...interface{}) {
+	b.finishStage(nil, StageResult{Message: fmt.Sprintf(format, a...), Status: StatusOK})
+}
+
+// StageFail finishes the current stage as failed. The error is printed
+// followed by every line written during the stage. A nil error is printed as
+// "failed".
+func (b *Buffer) StageFail(err error) {
+	b.finishStage(nil, failResult(err))
+}
+
+// failWith finishes the Stage as failed like Fail, but with only the lines
+// of dump printed below it.
+func (s *Stage) failWith(err error, dump *Log) {
+	r := failResult(err)
+	r.dump = dump
+	s.b.finishStage(s.state, r)
+}
+
+// failResult returns the outcome of a stage that failed with err.
+func failResult(err error) StageResult {
+	if err == nil {
+		return StageResult{Message: "failed", Status: StatusFail}
+	}
+	return StageResult{Message: err.Error(), Status: StatusFail, Err: err}
+}
+
+// StageWarn finishes the current stage with a warning and prints the message.
+func (b *Buffer) StageWarn(format string, a ...interface{}) {
+	b.finishStage(nil, StageResult{Message: fmt.Sprintf(format, a...), Status: StatusWarn})
+}
+
+// StageSkip finishes the current stage as skipped and prints the message.
+func (b *Buffer) StageSkip(format string, a ...interface{}) {
+	b.finishStage(nil, StageResult{Message: fmt.Sprintf(format, a...), Status: StatusSkip})
+}
+
+// BeginStage begins a named stage inside the current stage. The scroll window
+// and the message of the stage are indented by its depth.
+func (b *Buffer) BeginStage(name string) *Stage {
+	b.lock.RLock()
+	parent := b.current()
+	b.lock.RUnlock()
+	return b.beginStageIn(parent, name)
+}
+
+// beginStageIn hands a new stage inside parent to the Buffer goroutine.
+func (b *Buffer) beginStageIn(parent *stageState, name string) *Stage {
+	st := newStageState(name, parent, b.budget, b.limit)
+	select {
+	case b.beginner <- st:
+		<-b.done
+	case <-b.closed:
+	}
+	return &Stage{b: b, state: st}
+}
+
+// finishStage hands the stage outcome to the Buffer goroutine and waits for
+// it to be rendered. A nil stage finishes the current stage. Stages finished
+// once the Buffer is closed are dropped.
+func (b *Buffer) finishStage(st *stageState, s StageResult) {
+	if b.bufferMax == 0 {
+		panic("your buffer hasn't been initialized!")
+	}
+	select {
+	case b.stager <- stageRequest{result: s, stage: st}:
+		<-b.done
+	case <-b.closed:
+	}
+}
+
+// A Stage is a named stage begun with BeginStage. Lines written to a Stage
+// are only shown while it is the innermost open stage. Finishing a Stage
+// finishes any stage begun inside of it first. When a Stage is finished with
+// an empty message its name is used instead.
+//
+// Only the innermost stages are open at any time, so beginning a stage
+// finishes the stages begun inside its parent before, and a stage begun
+// inside a finished Stage is begun inside its closest open parent instead.
+// Lines written to a finished Stage go to its closest open parent.
+type Stage struct {
+	b     *Buffer
+	state *stageState
+}
+
+// stageState is the state of an open stage owned by the Buffer goroutine.
+type stageState struct {
+	name   string
+	depth  int
+	parent *stageState
+
+	// The lines of the scroll window, every line of the stage and its start
+	window   []Line
+	log      *Log
+	start    time.Time
+	finished bool
+}
+
+// newStageState creates the state for a stage inside parent with a Log of
+// the budget and line limit. Named stages inside the unnamed stage have a
+// depth of zero.
+func newStageState(name string, parent *stageState, budget, limit int) *stageState {
+	st := &stageState{
+		name:  name,
+		log:   newLog(budget, limit),
+		start: time.Now(),
+	}
+	st.setParent(parent)
+	return st
+}
+
+// setParent moves the stage inside parent.
+func (st *stageState) setParent(parent *stageState) {
+	st.parent = parent
+	st.depth = 0
+	if parent != nil && parent.name != "" {
+		st.depth = parent.depth + 1
+	}
+}
+
+// Name returns the name of the Stage.
+func (s *Stage) Name() string {
+	return s.state.name
+}
+
+// Log returns the Log of the Stage.
+func (s *Stage) Log() *Log {
+	return s.state.log
+}
+
+// BeginStage begins a named stage inside the Stage. A sibling stage that is
+// still open is finished first.
+func (s *Stage) BeginStage(name string) *Stage {
+	return s.b.beginStageIn(s.state, name)
+}
+
+// Printf formats the provided string to the Stage.
+func (s *Stage) Printf(format string, a ...interface{}) {
+	s.b.printTo(lineRequest{text: fmt.Sprintf(format, a...), stage: s.state})
+}
+
+// Println formats the provided operands to the Stage.
+func (s *Stage) Println(a ...interface{}) {
+	s.b.printTo(lineRequest{text: fmt.Sprint(a...), stage: s.state})
+}
+
+// End finishes the Stage like NewStage and prints the message.
+func (s *Stage) End(format string, a ...interface{}) {
+	s.b.finishStage(s.state, StageResult{Message: fmt.Sprintf(format, a...)})
+}
+
+// OK finishes the Stage as successful and prints the message.
+func (s *Stage) OK(format string, a ...interface{}) {
+	s.b.finishStage(s.state, StageResult{Message: fmt.Sprintf(format, a...), Status: StatusOK})
+}
+
+// Fail finishes the Stage as failed. The error is printed followed by every
+// line written during the Stage. A nil error is printed as "failed".
+func (s *Stage) Fail(err error) {
+	s.b.finishStage(s.state, failResult(err))
+}
+
+// Warn finishes the Stage with a warning and prints the message.
+func (s *Stage) Warn(format string, a ...interface{}) {
+	s.b.finishStage(s.state, StageResult{Message: fmt.Sprintf(format, a...), Status: StatusWarn})
+}
+
+// Skip finishes the Stage as skipped and prints the message.
+func (s *Stage) Skip(format string, a ...interface{}) {
+	s.b.finishStage(s.state, StageResult{Message: fmt.Sprintf(format, a...), Status: StatusSkip})
+}
+
+// BeginStage begins a named stage inside the current stage of the standard
+// Buffer.
+func BeginStage(name string) *Stage {
+	return std.BeginStage(name)
+}
+
+// StageOK finishes the current stage of the standard Buffer as successful.
+func StageOK(format string, a ...interface{}) {
+	std.StageOK(format, a...)
+}
+
+// StageFail finishes the current stage of the standard Buffer as failed.
+func StageFail(err error) {
+	std.StageFail(err)
+}
+
+// StageWarn finishes the current stage of the standard Buffer with a warning.
+func StageWarn(format string, a ...interface{}) {
+	std.StageWarn(format, a...)
+}
+
+// StageSkip finishes the current stage of the standard Buffer as skipped.
+func StageSkip(format string, a ...interface{}) {
+	std.StageSkip(format, a...)
+}
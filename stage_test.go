@@ -0,0 +1,202 @@
+package scroll_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/louislef299/scroll"
+	"github.com/louislef299/scroll/scrolltest"
+)
+
+// newTermBuffer creates a Buffer writing to a new virtual terminal.
+func newTermBuffer(t *testing.T, width, height, size int) (*scroll.Buffer, *scrolltest.Terminal) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	term := scrolltest.New(width, height)
+	buff := scroll.New(ctx, term, size)
+	buff.SetIsTerm(true)
+	buff.SetWidth(width)
+	return buff, term
+}
+
+func TestStageOutcomes(t *testing.T) {
+	buff, term := newTermBuffer(t, 40, 12, 2)
+	buff.SetPrefix("=>")
+
+	stages := []func(){
+		func() { buff.StageOK("built") },
+		func() { buff.StageWarn("tests flaky") },
+		func() { buff.StageSkip("publish skipped") },
+		func() { buff.StageFail(errors.New("deploy failed")) },
+	}
+	for i, finish := range stages {
+		for j := 0; j < 3; j++ {
+			buff.Printf("stage %d line %d", i, j)
+		}
+		finish()
+	}
+
+	want := []string{
+		"✔ built",
+		"⚠ tests flaky",
+		"↷ publish skipped",
+		"✖ deploy failed",
+		"  => stage 3 line 0",
+		"  => stage 3 line 1",
+		"  => stage 3 line 2",
+	}
+	if got := term.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+	scrolltest.AssertGolden(t, "outcomes", term.Golden())
+}
+
+func TestStageFailFullHistory(t *testing.T) {
+	buff, term := newTermBuffer(t, 40, 30, 3)
+	buff.SetStageColor(color.FgBlue)
+
+	for i := 0; i < 20; i++ {
+		buff.Printf("line %d", i)
+	}
+	buff.StageFail(errors.New("boom"))
+
+	lines := term.Lines()
+	if len(lines) != 21 {
+		t.Fatalf("expected the failure and 20 lines, got %q", lines)
+	}
+	if lines[1] != "  line 0" {
+		t.Fatalf("expected the first line of the stage, got %q", lines[1])
+	}
+	if got := term.Row(0)[0].Attr.FG; got != "31" {
+		t.Fatalf("expected failure color 31, got %q", got)
+	}
+}
+
+func TestNestedStages(t *testing.T) {
+	buff, term := newTermBuffer(t, 40, 12, 2)
+
+	buff.Printf("top level output")
+	deploy := buff.BeginStage("deploy")
+	deploy.Printf("preparing")
+
+	build := deploy.BeginStage("build image")
+	for i := 0; i < 3; i++ {
+		build.Printf("step %d", i)
+	}
+	build.OK("")
+
+	push := deploy.BeginStage("push layer")
+	push.Printf("pushing")
+	// finishing the parent closes the open child first
+	deploy.OK("deployed")
+	buff.NewStage("all done")
+
+	want := []string{
+		"  ✔ build image",
+		"  push layer",
+		"✔ deployed",
+		"all done",
+	}
+	if got := term.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+	scrolltest.AssertGolden(t, "nested", term.Golden())
+
+	stages := buff.Stages()
+	if len(stages) != 4 {
+		t.Fatalf("expected 4 finished stages, got %d", len(stages))
+	}
+	if s := stages[0]; s.Name != "build image" || s.Depth != 1 || s.Log.Len() != 3 {
+		t.Fatalf("unexpected child stage %+v", s)
+	}
+	if s := stages[2]; s.Name != "deploy" || s.Depth != 0 || s.Log.Len() != 1 {
+		t.Fatalf("unexpected parent stage %+v", s)
+	}
+	if s := stages[3]; s.Name != "" || s.Log.Len() != 1 {
+		t.Fatalf("unexpected unnamed stage %+v", s)
+	}
+}
+
+func TestNestedStageFail(t *testing.T) {
+	buff, term := newTermBuffer(t, 40, 12, 2)
+	buff.SetPrefix("=>")
+
+	deploy := buff.BeginStage("deploy")
+	build := deploy.BeginStage("build")
+	build.Printf("compiling")
+	build.Fail(errors.New("build failed"))
+	deploy.Fail(errors.New("deploy failed"))
+
+	want := []string{
+		"  ✖ build failed",
+		"    => compiling",
+		"✖ deploy failed",
+	}
+	if got := term.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+}
+
+func TestStageFailNil(t *testing.T) {
+	buff, term := newTermBuffer(t, 40, 12, 2)
+
+	buff.StageFail(nil)
+	buff.BeginStage("deploy").Fail(nil)
+
+	want := []string{"✖ failed", "✖ failed"}
+	if got := term.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+}
+
+func TestSiblingStages(t *testing.T) {
+	buff, term := newTermBuffer(t, 40, 12, 2)
+
+	deploy := buff.BeginStage("deploy")
+	a := deploy.BeginStage("a")
+	a.Printf("from a")
+	b := deploy.BeginStage("b")
+	b.Printf("from b")
+	a.OK("a done")
+	b.OK("b done")
+	deploy.OK("deployed")
+
+	want := []string{"  a", "  ✔ b done", "✔ deployed"}
+	if got := term.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+
+	var got []string
+	for _, s := range buff.Stages() {
+		got = append(got, fmt.Sprintf("%s %d %d", s.Name, s.Depth, s.Log.Len()))
+	}
+	if want := []string{"a 1 1", "b 1 1", "deploy 0 0"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected siblings inside deploy, got %q", got)
+	}
+}
+
+func TestStageInFinishedParent(t *testing.T) {
+	buff, term := newTermBuffer(t, 40, 12, 2)
+
+	deploy := buff.BeginStage("deploy")
+	build := deploy.BeginStage("build")
+	build.OK("built")
+	build.BeginStage("late").OK("late done")
+	build.Printf("late line")
+	deploy.OK("deployed")
+
+	want := []string{"  ✔ built", "  ✔ late done", "✔ deployed"}
+	if got := term.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected lines %q, got %q", want, got)
+	}
+	stages := buff.Stages()
+	if got := stages[len(stages)-1].Log.Len(); got != 1 {
+		t.Fatalf("expected the line of the finished stage in its parent, got %d lines", got)
+	}
+}
@@ -0,0 +1,65 @@
+package scroll
+
+import "io"
+
+// progressReader counts the bytes read from r on a byte-based Progress.
+type progressReader struct {
+	r io.Reader
+	p *Progress
+}
+
+// ProgressReader wraps r so every byte read from it drives a byte-based
+// progress bar of size bytes pinned to the Buffer. The bar is done once r
+// returns io.EOF or the returned reader is closed. Closing the reader also
+// closes r if it is an io.Closer, so it can wrap an http.Response.Body.
+func ProgressReader(b *Buffer, r io.Reader, size int64, label string) io.ReadCloser {
+	return &progressReader{r: r, p: b.newProgress(size, label, true)}
+}
+
+// Read implements io.Reader.
+func (pr *progressReader) Read(p []byte) (n int, err error) {
+	n, err = pr.r.Read(p)
+	pr.p.Add(int64(n))
+	if err == io.EOF {
+		pr.p.Done()
+	}
+	return n, err
+}
+
+// Close implements io.Closer.
+func (pr *progressReader) Close() error {
+	pr.p.Done()
+	if c, ok := pr.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// progressWriter counts the bytes written to w on a byte-based Progress.
+type progressWriter struct {
+	w io.Writer
+	p *Progress
+}
+
+// ProgressWriter wraps w so every byte written to it drives a byte-based
+// progress bar of size bytes pinned to the Buffer. The bar is done once the
+// returned writer is closed, which also closes w if it is an io.Closer.
+func ProgressWriter(b *Buffer, w io.Writer, size int64, label string) io.WriteCloser {
+	return &progressWriter{w: w, p: b.newProgress(size, label, true)}
+}
+
+// Write implements io.Writer.
+func (pw *progressWriter) Write(p []byte) (n int, err error) {
+	n, err = pw.w.Write(p)
+	pw.p.Add(int64(n))
+	return n, err
+}
+
+// Close implements io.Closer.
+func (pw *progressWriter) Close() error {
+	pw.p.Done()
+	if c, ok := pw.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}